@@ -5,21 +5,54 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"io/ioutil"
+	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
-	"strings"
+	"time"
 
 	"github.com/reviewpad/host-event-handler/handler"
 )
 
 var (
 	gitHubToken   = flag.String("github-token", "", "GitHub Personal Access Token (PAT)")
-	eventFilePath = flag.String("event-payload", "", "File path to github action event")
+	eventFilePath = flag.String("event-payload", "", "File path to github action event, or to a webhook payload when -event-name is set (\"-\" reads from stdin)")
+	eventName     = flag.String("event-name", "", "Name of the event of a webhook payload (e.g. pull_request), as in the X-GitHub-Event header")
+	deliveryID    = flag.String("delivery-id", "", "ID of the delivery of a webhook payload, as in the X-GitHub-Delivery header, which is logged with the event")
+
+	appID             = flag.Int64("github-app-id", 0, "GitHub App ID, to authenticate as a GitHub App instead of with a token")
+	appPrivateKeyPath = flag.String("github-app-private-key", "", "File path to the private key of the GitHub App")
+	appInstallationID = flag.Int64("github-app-installation-id", 0, "GitHub App installation ID, looked up by repository when not in the event")
+
+	cacheDir  = flag.String("cache-dir", "", "Directory where the GitHub API responses are cached between runs")
+	cacheSize = flag.Int("cache-size", 64, "Maximum size in MiB of the GitHub API responses cached in memory in serve mode, when -cache-dir is not set")
+
+	logLevel  = flag.String("log-level", "info", "Minimum level of the logs (debug, info, warn or error)")
+	logFormat = flag.String("log-format", "text", "Format of the logs (text, json or actions)")
+
+	allowedActions         = flag.String("allowed-actions", "", "Actions processed by event name, e.g. \"pull_request: [opened, synchronize]; issues: opened\"")
+	deniedActions          = flag.String("denied-actions", "", "Actions skipped by event name, e.g. \"pull_request: [labeled, edited]\"")
+	botLogins              = flag.String("bot-logins", "", "Logins whose events are skipped, separated by commas")
+	deploymentEnvironments = flag.String("deployment-environments", "", "Environments of the deployment events that are processed, separated by commas")
+	deploymentStates       = flag.String("deployment-states", "", "States of the deployment_status events that are processed, separated by commas")
+	releaseMaxCommits      = flag.Int("release-max-commits", 0, "Maximum number of commits of a release mapped to their pull requests (default 250)")
+	linkedEntities         = flag.Bool("linked-entities", false, "Expand pull requests to the issues they close, and issues to the pull requests that close them")
+	ignoreBots             = flag.Bool("ignore-bots", true, "Skip the events of every GitHub App (e.g. dependabot[bot]), not only the events of reviewpad itself")
+	includeClosed          = flag.Bool("include-closed", false, "Keep the closed pull requests, issues and discussions among the targets")
+	includeMerged          = flag.Bool("include-merged", false, "Keep the merged pull requests among the targets")
+	includeLocked          = flag.Bool("include-locked", false, "Keep the locked pull requests, issues and discussions among the targets")
+
+	addr                  = flag.String("addr", ":8080", "Address on which the webhooks are received in serve mode, at /webhook, and the metrics are exposed, at /metrics")
+	webhookSecret         = flag.String("webhook-secret", "", "Secret of the webhook, used to verify the deliveries in serve mode")
+	insecureSkipSignature = flag.Bool("insecure-skip-signature", false, "Accept the deliveries without verifying them in serve mode when -webhook-secret is not set")
+	shaResolverWindow     = flag.Duration("sha-resolver-window", time.Millisecond*100, "Time during which the head SHAs of the events are batched into a single GraphQL query in serve mode (0 disables the batching)")
 )
 
 func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %v [flags] [explain|serve]\n", os.Args[0])
 	flag.PrintDefaults()
 	os.Exit(2)
 }
@@ -31,28 +64,306 @@ func main() {
 		usage()
 	}
 
-	if *gitHubToken == "" {
+	if *gitHubToken == "" && *appID == 0 {
 		log.Printf("missing argument token")
 		usage()
 	}
 
+	if *appID != 0 && *appPrivateKeyPath == "" {
+		log.Printf("missing argument github app private key")
+		usage()
+	}
+
+	logger, err := getLogger()
+	if err != nil {
+		log.Print(err)
+		usage()
+	}
+	handler.SetDefaultLogger(logger)
+
+	if flag.Arg(0) == "serve" {
+		if *webhookSecret == "" && !*insecureSkipSignature {
+			log.Printf("missing argument webhook secret")
+			usage()
+		}
+
+		serve()
+		return
+	}
+
 	if *eventFilePath == "" {
 		log.Printf("missing argument event")
 		usage()
 	}
 
-	content, err := ioutil.ReadFile(*eventFilePath)
+	content, err := readEventFile(*eventFilePath)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Replace GitHub token on event by PAT
-	rawEvent := strings.Replace(string(content), "***", *gitHubToken, 1)
+	event, err := parseEvent(content)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *deliveryID != "" {
+		event.DeliveryID = deliveryID
+	}
 
-	event, err := handler.ParseEvent(rawEvent)
+	opts, err := getOptions()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	handler.ProcessEvent(event)
+	if *appID != 0 {
+		appOpt, err := authenticateApp(event)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, appOpt)
+	} else {
+		event.Token = gitHubToken
+	}
+
+	if flag.Arg(0) == "explain" {
+		explain(event, opts)
+		return
+	}
+
+	handler.ProcessEvent(event, opts...)
+}
+
+func getOptions() ([]handler.Option, error) {
+	opts := []handler.Option{
+		handler.WithReleaseMaxCommits(*releaseMaxCommits),
+	}
+
+	actionLists := []struct {
+		value       string
+		withActions func(eventName string, actions ...string) handler.Option
+	}{
+		{*allowedActions, handler.WithAllowedActions},
+		{*deniedActions, handler.WithDeniedActions},
+	}
+
+	for _, actionList := range actionLists {
+		actions, err := handler.ParseActionList(actionList.value)
+		if err != nil {
+			return nil, err
+		}
+		for eventName, eventActions := range actions {
+			opts = append(opts, actionList.withActions(eventName, eventActions...))
+		}
+	}
+
+	if logins := handler.SplitList(*botLogins); len(logins) > 0 {
+		opts = append(opts, handler.WithBotLogins(logins...))
+	}
+
+	if environments := handler.SplitList(*deploymentEnvironments); len(environments) > 0 {
+		opts = append(opts, handler.WithDeploymentEnvironments(environments...))
+	}
+
+	if states := handler.SplitList(*deploymentStates); len(states) > 0 {
+		opts = append(opts, handler.WithDeploymentStates(states...))
+	}
+
+	if *linkedEntities {
+		opts = append(opts, handler.WithLinkedEntities())
+	}
+
+	if !*ignoreBots {
+		opts = append(opts, handler.WithoutBotSenderDetection())
+	}
+
+	if *includeClosed {
+		opts = append(opts, handler.WithClosedEntities())
+	}
+
+	if *includeMerged {
+		opts = append(opts, handler.WithMergedEntities())
+	}
+
+	if *includeLocked {
+		opts = append(opts, handler.WithLockedEntities())
+	}
+
+	if *cacheDir != "" {
+		cache, err := handler.NewDiskCache(*cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, handler.WithCache(cache))
+	}
+
+	// The tokens of the installation expire every hour, but its responses can be reused.
+	if *appID != 0 && *appInstallationID != 0 {
+		opts = append(opts, handler.WithCacheIdentity(fmt.Sprintf("installation %v", *appInstallationID)))
+	}
+
+	return opts, nil
+}
+
+// getLogger returns the logger with the level and format of the flags.
+func getLogger() (*handler.Logger, error) {
+	level, err := handler.ParseLevel(*logLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := handler.ParseEncoder(*logFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler.NewLogger(os.Stderr, level, encoder), nil
+}
+
+// readEventFile reads the event from the given path, or from stdin when the path is "-".
+func readEventFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	return os.ReadFile(path)
+}
+
+// authenticateApp sets the token of the event to an installation token of the GitHub App
+// and returns the option that skips the events triggered by the GitHub App itself.
+func authenticateApp(event *handler.ActionEvent) (handler.Option, error) {
+	source, err := getAppTokenSource(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := source.Authenticate(context.Background(), event); err != nil {
+		return nil, err
+	}
+
+	return getAppBotLogin(source)
+}
+
+// getAppBotLogin returns the option that skips the events triggered by the GitHub App itself.
+func getAppBotLogin(source *handler.AppTokenSource) (handler.Option, error) {
+	login, err := source.Login(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return handler.WithBotLogins(login), nil
+}
+
+// getAppTokenSource returns the token source of the GitHub App of the flags,
+// whose requests are counted in the metrics when they are set.
+func getAppTokenSource(metrics *handler.Metrics) (*handler.AppTokenSource, error) {
+	privateKey, err := os.ReadFile(*appPrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler.NewAppTokenSource(&handler.AppConfig{
+		AppID:          *appID,
+		PrivateKey:     privateKey,
+		InstallationID: *appInstallationID,
+		Metrics:        metrics,
+	})
+}
+
+// serve receives the webhooks delivered by GitHub and responds with the targets of their events,
+// authenticating either as the GitHub App or with the token of the flags.
+func serve() {
+	authenticate := func(ctx context.Context, event *handler.ActionEvent) error {
+		event.Token = gitHubToken
+		return nil
+	}
+
+	opts, err := getOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The responses are revalidated across the events received by the server.
+	if *cacheDir == "" {
+		opts = append(opts, handler.WithCache(handler.NewMemoryCache(*cacheSize<<20)))
+	}
+
+	if *shaResolverWindow > 0 {
+		opts = append(opts, handler.WithShaResolver(handler.NewShaResolver(*shaResolverWindow)))
+	}
+
+	metrics := handler.NewMetrics()
+
+	if *appID != 0 {
+		source, err := getAppTokenSource(metrics)
+		if err != nil {
+			log.Fatal(err)
+		}
+		authenticate = source.Authenticate
+
+		appOpt, err := getAppBotLogin(source)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, appOpt)
+	}
+
+	server := handler.NewServer(&handler.ServerConfig{
+		WebhookSecret:         []byte(*webhookSecret),
+		InsecureSkipSignature: *insecureSkipSignature,
+		Authenticate:          authenticate,
+		Metrics:               metrics,
+		Options:               opts,
+	})
+
+	handler.DefaultLogger().Info("listening", handler.Fields{"addr": *addr})
+
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
+
+// parseEvent parses either a github action event or, when the event name is set,
+// the payload of a webhook delivery.
+func parseEvent(content []byte) (*handler.ActionEvent, error) {
+	if *eventName != "" {
+		return handler.NewActionEventFromWebhook(*eventName, content)
+	}
+
+	return handler.ParseEvent(string(content))
+}
+
+// explain prints every candidate considered for the event and why it was included or dropped.
+func explain(event *handler.ActionEvent, opts []handler.Option) {
+	explanation, err := handler.ExplainEvent(event, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("event: %v", explanation.EventName)
+	if explanation.Action != "" {
+		fmt.Printf(" (action: %v)", explanation.Action)
+	}
+	fmt.Println()
+
+	if explanation.SkipReason != "" {
+		fmt.Printf("skipped: %v\n", explanation.SkipReason)
+		return
+	}
+
+	if len(explanation.Candidates) == 0 {
+		fmt.Println("no candidates")
+		return
+	}
+
+	for _, candidate := range explanation.Candidates {
+		target := candidate.Target
+		if candidate.Included {
+			fmt.Printf("+ %v/%v %v #%v: %v\n", target.Owner, target.Repo, target.Kind, target.Number, target.Reason)
+			continue
+		}
+
+		fmt.Printf("- %v/%v %v #%v: %v", target.Owner, target.Repo, target.Kind, target.Number, candidate.Reason)
+		if target.Reason != "" {
+			fmt.Printf(" (%v)", target.Reason)
+		}
+		fmt.Println()
+	}
 }
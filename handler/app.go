@@ -0,0 +1,383 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// appJWTLifetime is the lifetime of the JWTs signed for the GitHub App.
+	// GitHub rejects JWTs that expire more than 10 minutes in the future.
+	appJWTLifetime = time.Minute * 9
+
+	// appJWTClockDrift backdates the JWTs to allow for clock drift with GitHub.
+	appJWTClockDrift = time.Minute
+
+	// installationTokenRefreshMargin is how long before its expiry an installation token is refreshed,
+	// so that it does not expire while the event is being processed.
+	installationTokenRefreshMargin = time.Minute * 5
+)
+
+// AppConfig identifies a GitHub App and, optionally, the installation to authenticate as.
+type AppConfig struct {
+	// AppID is the ID of the GitHub App.
+	AppID int64
+	// PrivateKey is the PEM encoded private key of the GitHub App.
+	PrivateKey []byte
+	// InstallationID is the installation used when the event does not reference one.
+	// When it is not set, the installation is looked up by the repository of the event.
+	InstallationID int64
+	// BaseURL is the URL of the GitHub API. Defaults to https://api.github.com/.
+	BaseURL string
+	// Metrics count the requests made as the GitHub App along with the other requests
+	// to the GitHub API, when set.
+	Metrics *Metrics
+	// TracerProvider records the spans of the requests made as the GitHub App.
+	// Defaults to the global tracer provider.
+	TracerProvider trace.TracerProvider
+}
+
+// AppTokenSource provides installation tokens of a GitHub App, which are cached
+// until shortly before they expire.
+// For more information, visit: https://docs.github.com/en/developers/apps/building-github-apps/authenticating-with-github-apps
+type AppTokenSource struct {
+	appID          int64
+	privateKey     *rsa.PrivateKey
+	installationID int64
+	client         *github.Client
+
+	// mu guards the caches, but not the requests that fill them, which are shared
+	// by the concurrent lookups of the same installation or repository instead.
+	mu                   sync.Mutex
+	tokens               map[int64]*github.InstallationToken
+	pendingTokens        map[int64]*appRequest
+	repoInstallationID   map[string]int64
+	pendingInstallations map[string]*appRequest
+}
+
+// appRequest is a request to the GitHub API made as the GitHub App, whose result
+// is shared by the lookups that wait for it.
+type appRequest struct {
+	done chan struct{}
+
+	token          *github.InstallationToken
+	installationID int64
+	err            error
+}
+
+// wait waits for the result of the request, unless the context is done before.
+func (r *appRequest) wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return r.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NewAppTokenSource returns a token source for the GitHub App in the given configuration.
+func NewAppTokenSource(config *AppConfig) (*AppTokenSource, error) {
+	if config.AppID == 0 {
+		return nil, fmt.Errorf("missing app id")
+	}
+
+	privateKey, err := parsePrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse app private key: %w", err)
+	}
+
+	source := &AppTokenSource{
+		appID:                config.AppID,
+		privateKey:           privateKey,
+		installationID:       config.InstallationID,
+		tokens:               make(map[int64]*github.InstallationToken),
+		pendingTokens:        make(map[int64]*appRequest),
+		repoInstallationID:   make(map[string]int64),
+		pendingInstallations: make(map[string]*appRequest),
+	}
+
+	var base http.RoundTripper
+	if config.Metrics != nil {
+		base = &metricsTransport{metrics: config.Metrics}
+	}
+
+	tracerProvider := config.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+
+	source.client = github.NewClient(&http.Client{
+		Transport: &tracingTransport{
+			base:   &appTransport{base: base, source: source},
+			tracer: tracerProvider.Tracer(tracerName),
+		},
+	})
+
+	if config.BaseURL != "" {
+		baseURL, err := url.Parse(strings.TrimSuffix(config.BaseURL, "/") + "/")
+		if err != nil {
+			return nil, fmt.Errorf("parse base url: %w", err)
+		}
+		source.client.BaseURL = baseURL
+	}
+
+	return source, nil
+}
+
+// Authenticate sets the token of the event to an installation token of the GitHub App.
+func (s *AppTokenSource) Authenticate(ctx context.Context, event *ActionEvent) error {
+	ctx, canc := context.WithTimeout(ctx, time.Minute*10)
+	defer canc()
+
+	token, err := s.Token(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	event.Token = &token
+
+	return nil
+}
+
+// Token returns an installation token for the installation of the event.
+// The installation is taken from the event payload, which is set for the webhooks
+// delivered to the GitHub App, from the configuration or looked up by the repository
+// of the event, in this order.
+func (s *AppTokenSource) Token(ctx context.Context, event *ActionEvent) (string, error) {
+	installationID, err := s.getInstallationID(ctx, event)
+	if err != nil {
+		return "", fmt.Errorf("get installation: %w", err)
+	}
+
+	s.mu.Lock()
+
+	if token, ok := s.tokens[installationID]; ok && time.Until(token.GetExpiresAt()) > installationTokenRefreshMargin {
+		s.mu.Unlock()
+		return token.GetToken(), nil
+	}
+
+	// The token is being created for another event of the same installation.
+	if req, ok := s.pendingTokens[installationID]; ok {
+		s.mu.Unlock()
+
+		if err := req.wait(ctx); err != nil {
+			return "", err
+		}
+		return req.token.GetToken(), nil
+	}
+
+	req := &appRequest{done: make(chan struct{})}
+	s.pendingTokens[installationID] = req
+	s.mu.Unlock()
+
+	DefaultLogger().Debug("creating installation token", Fields{"installation_id": installationID})
+
+	req.token, _, req.err = s.client.Apps.CreateInstallationToken(ctx, installationID, nil)
+	if req.err != nil {
+		req.err = fmt.Errorf("create installation token: %w", req.err)
+	}
+
+	s.mu.Lock()
+	delete(s.pendingTokens, installationID)
+	if req.err == nil {
+		s.tokens[installationID] = req.token
+	}
+	s.mu.Unlock()
+
+	close(req.done)
+
+	if req.err != nil {
+		return "", req.err
+	}
+
+	return req.token.GetToken(), nil
+}
+
+// Login returns the login of the GitHub App, e.g. "reviewpad[bot]", which is the sender
+// of the events triggered by the changes made with its installation tokens.
+func (s *AppTokenSource) Login(ctx context.Context) (string, error) {
+	app, _, err := s.client.Apps.Get(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("get app: %w", err)
+	}
+
+	return app.GetSlug() + "[bot]", nil
+}
+
+func (s *AppTokenSource) getInstallationID(ctx context.Context, event *ActionEvent) (int64, error) {
+	if installationID := getEventInstallationID(event); installationID != 0 {
+		return installationID, nil
+	}
+
+	if s.installationID != 0 {
+		return s.installationID, nil
+	}
+
+	if event.Repository == nil {
+		return 0, fmt.Errorf("missing repository")
+	}
+
+	repository := *event.Repository
+
+	repoParts := strings.SplitN(repository, "/", 2)
+	if len(repoParts) != 2 {
+		return 0, fmt.Errorf("invalid repository %v", repository)
+	}
+
+	s.mu.Lock()
+
+	if installationID, ok := s.repoInstallationID[repository]; ok {
+		s.mu.Unlock()
+		return installationID, nil
+	}
+
+	// The installation is being looked up for another event of the same repository.
+	if req, ok := s.pendingInstallations[repository]; ok {
+		s.mu.Unlock()
+
+		if err := req.wait(ctx); err != nil {
+			return 0, err
+		}
+		return req.installationID, nil
+	}
+
+	req := &appRequest{done: make(chan struct{})}
+	s.pendingInstallations[repository] = req
+	s.mu.Unlock()
+
+	DefaultLogger().Debug("looking for the installation of the repository", Fields{"repository": repository})
+
+	installation, _, err := s.client.Apps.FindRepositoryInstallation(ctx, repoParts[0], repoParts[1])
+	req.installationID, req.err = installation.GetID(), err
+
+	s.mu.Lock()
+	delete(s.pendingInstallations, repository)
+	if req.err == nil {
+		s.repoInstallationID[repository] = req.installationID
+	}
+	s.mu.Unlock()
+
+	close(req.done)
+
+	return req.installationID, req.err
+}
+
+// getEventInstallationID returns the installation of the GitHub App that the event was delivered to.
+// Events delivered to GitHub Actions have no installation.
+func getEventInstallationID(event *ActionEvent) int64 {
+	if event.EventPayload == nil {
+		return 0
+	}
+
+	payload := struct {
+		Installation struct {
+			ID int64 `json:"id"`
+		} `json:"installation"`
+	}{}
+
+	if err := json.Unmarshal(*event.EventPayload, &payload); err != nil {
+		return 0
+	}
+
+	return payload.Installation.ID
+}
+
+// appTransport authenticates the requests as the GitHub App with a JWT signed for each request.
+type appTransport struct {
+	base   http.RoundTripper
+	source *AppTokenSource
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	jwt, err := signAppJWT(t.source.appID, t.source.privateKey, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("sign app jwt: %w", err)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(req)
+}
+
+// signAppJWT returns a JWT signed with RS256 that authenticates as the GitHub App.
+// For more information, visit: https://docs.github.com/en/developers/apps/building-github-apps/authenticating-with-github-apps#authenticating-as-a-github-app
+func signAppJWT(appID int64, privateKey *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-appJWTClockDrift).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey parses a PEM encoded RSA private key, either in the PKCS #1 format
+// of the keys generated by GitHub or in the PKCS #8 format.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid pem")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an rsa private key")
+	}
+
+	return rsaKey, nil
+}
@@ -0,0 +1,486 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+// appServer is a stand-in for the GitHub API endpoints used to authenticate as a GitHub App.
+type appServer struct {
+	*httptest.Server
+
+	publicKey *rsa.PublicKey
+	appID     int64
+	expiresIn time.Duration
+
+	mu       sync.Mutex
+	requests []string
+	tokens   int
+}
+
+func newAppServer(t *testing.T, publicKey *rsa.PublicKey, appID int64, expiresIn time.Duration) *appServer {
+	server := &appServer{
+		publicKey: publicKey,
+		appID:     appID,
+		expiresIn: expiresIn,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/reviewpad/reviewpad/installation", func(w http.ResponseWriter, r *http.Request) {
+		if !server.authorize(w, r) {
+			return
+		}
+		fmt.Fprint(w, `{"id": 7}`)
+	})
+	mux.HandleFunc("/app", func(w http.ResponseWriter, r *http.Request) {
+		if !server.authorize(w, r) {
+			return
+		}
+		fmt.Fprintf(w, `{"id": %v, "slug": "reviewpad"}`, appID)
+	})
+	mux.HandleFunc("/app/installations/", func(w http.ResponseWriter, r *http.Request) {
+		if !server.authorize(w, r) {
+			return
+		}
+
+		installationID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/app/installations/"), "/access_tokens")
+
+		server.mu.Lock()
+		server.tokens++
+		token := fmt.Sprintf("ghs_%v_%v", installationID, server.tokens)
+		server.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      token,
+			"expires_at": time.Now().Add(server.expiresIn).UTC().Format(time.RFC3339),
+		})
+	})
+
+	server.Server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// authorize checks that the request is authenticated with a valid JWT signed by the GitHub App.
+func (s *appServer) authorize(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	s.requests = append(s.requests, fmt.Sprintf("%v %v", r.Method, r.URL.Path))
+	s.mu.Unlock()
+
+	jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		http.Error(w, "invalid jwt", http.StatusUnauthorized)
+		return false
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		http.Error(w, "invalid jwt signature", http.StatusUnauthorized)
+		return false
+	}
+
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(s.publicKey, crypto.SHA256, hash[:], signature); err != nil {
+		http.Error(w, "invalid jwt signature", http.StatusUnauthorized)
+		return false
+	}
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		http.Error(w, "invalid jwt claims", http.StatusUnauthorized)
+		return false
+	}
+
+	claims := struct {
+		IssuedAt  int64 `json:"iat"`
+		ExpiresAt int64 `json:"exp"`
+		Issuer    int64 `json:"iss"`
+	}{}
+	if err := json.Unmarshal(rawClaims, &claims); err != nil {
+		http.Error(w, "invalid jwt claims", http.StatusUnauthorized)
+		return false
+	}
+
+	now := time.Now().Unix()
+	if claims.Issuer != s.appID || claims.IssuedAt > now || claims.ExpiresAt < now || claims.ExpiresAt-now > 600 {
+		http.Error(w, "invalid jwt claims", http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+func (s *appServer) getRequests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func generatePrivateKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return privateKey, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+}
+
+func TestAppTokenSource_Token(t *testing.T) {
+	privateKey, privateKeyPEM := generatePrivateKey(t)
+
+	tests := map[string]struct {
+		event          *handler.ActionEvent
+		installationID int64
+		wantToken      string
+		wantRequests   []string
+	}{
+		"installation_in_payload": {
+			event: &handler.ActionEvent{
+				EventName:    github.String("pull_request"),
+				EventPayload: buildPayload([]byte(`{"installation": {"id": 42}}`)),
+				Repository:   github.String("reviewpad/reviewpad"),
+			},
+			installationID: 1,
+			wantToken:      "ghs_42_1",
+			wantRequests: []string{
+				"POST /app/installations/42/access_tokens",
+			},
+		},
+		"installation_in_config": {
+			event: &handler.ActionEvent{
+				EventName:    github.String("pull_request"),
+				EventPayload: buildPayload([]byte(`{"action": "opened"}`)),
+				Repository:   github.String("reviewpad/reviewpad"),
+			},
+			installationID: 1,
+			wantToken:      "ghs_1_1",
+			wantRequests: []string{
+				"POST /app/installations/1/access_tokens",
+			},
+		},
+		"installation_of_repository": {
+			event: &handler.ActionEvent{
+				EventName:  github.String("schedule"),
+				Repository: github.String("reviewpad/reviewpad"),
+			},
+			wantToken: "ghs_7_1",
+			wantRequests: []string{
+				"GET /repos/reviewpad/reviewpad/installation",
+				"POST /app/installations/7/access_tokens",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := newAppServer(t, &privateKey.PublicKey, 123, time.Hour)
+
+			source, err := handler.NewAppTokenSource(&handler.AppConfig{
+				AppID:          123,
+				PrivateKey:     privateKeyPEM,
+				InstallationID: test.installationID,
+				BaseURL:        server.URL,
+			})
+			assert.Nil(t, err)
+
+			gotToken, err := source.Token(context.Background(), test.event)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantToken, gotToken)
+			assert.Equal(t, test.wantRequests, server.getRequests())
+		})
+	}
+}
+
+func TestAppTokenSource_Token_Cache(t *testing.T) {
+	privateKey, privateKeyPEM := generatePrivateKey(t)
+
+	tests := map[string]struct {
+		expiresIn    time.Duration
+		wantTokens   []string
+		wantRequests []string
+	}{
+		"cached": {
+			expiresIn:  time.Hour,
+			wantTokens: []string{"ghs_7_1", "ghs_7_1"},
+			wantRequests: []string{
+				"GET /repos/reviewpad/reviewpad/installation",
+				"POST /app/installations/7/access_tokens",
+			},
+		},
+		"refreshed_before_expiry": {
+			expiresIn:  time.Minute * 2,
+			wantTokens: []string{"ghs_7_1", "ghs_7_2"},
+			wantRequests: []string{
+				"GET /repos/reviewpad/reviewpad/installation",
+				"POST /app/installations/7/access_tokens",
+				"POST /app/installations/7/access_tokens",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := newAppServer(t, &privateKey.PublicKey, 123, test.expiresIn)
+
+			source, err := handler.NewAppTokenSource(&handler.AppConfig{
+				AppID:      123,
+				PrivateKey: privateKeyPEM,
+				BaseURL:    server.URL,
+			})
+			assert.Nil(t, err)
+
+			event := &handler.ActionEvent{
+				EventName:  github.String("schedule"),
+				Repository: github.String("reviewpad/reviewpad"),
+			}
+
+			gotTokens := make([]string, 0)
+			for range test.wantTokens {
+				token, err := source.Token(context.Background(), event)
+				assert.Nil(t, err)
+				gotTokens = append(gotTokens, token)
+			}
+
+			assert.Equal(t, test.wantTokens, gotTokens)
+			assert.Equal(t, test.wantRequests, server.getRequests())
+		})
+	}
+}
+
+func TestAppTokenSource_Token_Concurrent(t *testing.T) {
+	privateKey, privateKeyPEM := generatePrivateKey(t)
+	server := newAppServer(t, &privateKey.PublicKey, 123, time.Hour)
+
+	source, err := handler.NewAppTokenSource(&handler.AppConfig{
+		AppID:      123,
+		PrivateKey: privateKeyPEM,
+		BaseURL:    server.URL,
+	})
+	assert.Nil(t, err)
+
+	event := &handler.ActionEvent{
+		EventName:  github.String("schedule"),
+		Repository: github.String("reviewpad/reviewpad"),
+	}
+
+	var wg sync.WaitGroup
+	gotTokens := make([]string, 10)
+	for i := range gotTokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			token, err := source.Token(context.Background(), event)
+			assert.Nil(t, err)
+			gotTokens[i] = token
+		}(i)
+	}
+	wg.Wait()
+
+	for _, token := range gotTokens {
+		assert.Equal(t, "ghs_7_1", token)
+	}
+	assert.Equal(t, []string{
+		"GET /repos/reviewpad/reviewpad/installation",
+		"POST /app/installations/7/access_tokens",
+	}, server.getRequests())
+}
+
+func TestAppTokenSource_Authenticate(t *testing.T) {
+	privateKey, privateKeyPEM := generatePrivateKey(t)
+	server := newAppServer(t, &privateKey.PublicKey, 123, time.Hour)
+
+	source, err := handler.NewAppTokenSource(&handler.AppConfig{
+		AppID:          123,
+		PrivateKey:     privateKeyPEM,
+		InstallationID: 1,
+		BaseURL:        server.URL,
+	})
+	assert.Nil(t, err)
+
+	event := &handler.ActionEvent{
+		EventName: github.String("schedule"),
+	}
+
+	err = source.Authenticate(context.Background(), event)
+
+	assert.Nil(t, err)
+	assert.Equal(t, github.String("ghs_1_1"), event.Token)
+}
+
+func TestAppTokenSource_Login(t *testing.T) {
+	privateKey, privateKeyPEM := generatePrivateKey(t)
+	server := newAppServer(t, &privateKey.PublicKey, 123, time.Hour)
+
+	source, err := handler.NewAppTokenSource(&handler.AppConfig{
+		AppID:      123,
+		PrivateKey: privateKeyPEM,
+		BaseURL:    server.URL,
+	})
+	assert.Nil(t, err)
+
+	login, err := source.Login(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, "reviewpad[bot]", login)
+	assert.Equal(t, []string{"GET /app"}, server.getRequests())
+}
+
+func TestAppTokenSource_WithTracerProvider(t *testing.T) {
+	privateKey, privateKeyPEM := generatePrivateKey(t)
+	server := newAppServer(t, &privateKey.PublicKey, 123, time.Hour)
+
+	provider, exporter := newTracerProvider()
+	metrics := handler.NewMetrics()
+
+	source, err := handler.NewAppTokenSource(&handler.AppConfig{
+		AppID:          123,
+		PrivateKey:     privateKeyPEM,
+		BaseURL:        server.URL,
+		Metrics:        metrics,
+		TracerProvider: provider,
+	})
+	assert.Nil(t, err)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "authenticate")
+	_, err = source.Token(ctx, &handler.ActionEvent{
+		EventName:  github.String("schedule"),
+		Repository: github.String("reviewpad/reviewpad"),
+	})
+	parent.End()
+
+	assert.Nil(t, err)
+
+	// The requests made as the GitHub App are traced in the context of the lookup.
+	spans := spansByName(exporter)
+	for _, name := range []string{"GitHub GET /repos/reviewpad/reviewpad/installation", "GitHub POST /app/installations/7/access_tokens"} {
+		span, ok := spans[name]
+		assert.True(t, ok, name)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID(), name)
+	}
+
+	gotVal := scrapeMetrics(t, metrics.Handler())
+
+	assert.Contains(t, gotVal, `host_event_handler_github_requests_total{code="200",method="GET"} 1`)
+	assert.Contains(t, gotVal, `host_event_handler_github_requests_total{code="201",method="POST"} 1`)
+}
+
+func TestAppTokenSource_Token_Failure(t *testing.T) {
+	privateKey, privateKeyPEM := generatePrivateKey(t)
+	_, otherPrivateKeyPEM := generatePrivateKey(t)
+
+	tests := map[string]struct {
+		privateKeyPEM []byte
+		event         *handler.ActionEvent
+	}{
+		"missing_repository": {
+			privateKeyPEM: privateKeyPEM,
+			event: &handler.ActionEvent{
+				EventName: github.String("schedule"),
+			},
+		},
+		"invalid_repository": {
+			privateKeyPEM: privateKeyPEM,
+			event: &handler.ActionEvent{
+				EventName:  github.String("schedule"),
+				Repository: github.String("reviewpad"),
+			},
+		},
+		"unknown_repository": {
+			privateKeyPEM: privateKeyPEM,
+			event: &handler.ActionEvent{
+				EventName:  github.String("schedule"),
+				Repository: github.String("reviewpad/docs"),
+			},
+		},
+		"wrong_private_key": {
+			privateKeyPEM: otherPrivateKeyPEM,
+			event: &handler.ActionEvent{
+				EventName:    github.String("pull_request"),
+				EventPayload: buildPayload([]byte(`{"installation": {"id": 42}}`)),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := newAppServer(t, &privateKey.PublicKey, 123, time.Hour)
+
+			source, err := handler.NewAppTokenSource(&handler.AppConfig{
+				AppID:      123,
+				PrivateKey: test.privateKeyPEM,
+				BaseURL:    server.URL,
+			})
+			assert.Nil(t, err)
+
+			gotToken, err := source.Token(context.Background(), test.event)
+
+			assert.NotNil(t, err)
+			assert.Equal(t, "", gotToken)
+		})
+	}
+}
+
+func TestNewAppTokenSource_Failure(t *testing.T) {
+	_, privateKeyPEM := generatePrivateKey(t)
+
+	tests := map[string]struct {
+		config *handler.AppConfig
+	}{
+		"missing_app_id": {
+			config: &handler.AppConfig{
+				PrivateKey: privateKeyPEM,
+			},
+		},
+		"invalid_private_key": {
+			config: &handler.AppConfig{
+				AppID:      123,
+				PrivateKey: []byte("not a private key"),
+			},
+		},
+		"invalid_base_url": {
+			config: &handler.AppConfig{
+				AppID:      123,
+				PrivateKey: privateKeyPEM,
+				BaseURL:    "://api.github.com",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.NewAppTokenSource(test.config)
+
+			assert.NotNil(t, err)
+			assert.Nil(t, gotVal)
+		})
+	}
+}
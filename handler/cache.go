@@ -0,0 +1,270 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cache stores the responses of the GitHub API by key.
+type Cache interface {
+	// Get returns the response stored with the key, if any.
+	Get(key string) ([]byte, bool)
+	// Set stores the response with the key.
+	Set(key string, response []byte) error
+}
+
+// defaultMemoryCacheSize is the default maximum size of the responses kept by a MemoryCache.
+const defaultMemoryCacheSize = 64 << 20
+
+// MemoryCache is a Cache that keeps the responses in memory up to a maximum size,
+// evicting the least recently used responses when it is exceeded.
+type MemoryCache struct {
+	maxSize int
+
+	mu        sync.Mutex
+	size      int
+	responses map[string]*list.Element
+	// lru orders the responses from the most to the least recently used.
+	lru *list.List
+}
+
+type memoryCacheEntry struct {
+	key      string
+	response []byte
+}
+
+// NewMemoryCache returns an empty MemoryCache that keeps up to maxSize bytes of responses.
+// Values below 1 keep the default of 64 MiB.
+func NewMemoryCache(maxSize int) *MemoryCache {
+	if maxSize < 1 {
+		maxSize = defaultMemoryCacheSize
+	}
+
+	return &MemoryCache{
+		maxSize:   maxSize,
+		responses: make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.responses[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+
+	return elem.Value.(*memoryCacheEntry).response, true
+}
+
+func (c *MemoryCache) Set(key string, response []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.responses[key]; ok {
+		c.remove(elem)
+	}
+
+	// Responses larger than the cache are not kept, rather than evicting every other response.
+	if len(response) > c.maxSize {
+		return nil
+	}
+
+	c.responses[key] = c.lru.PushFront(&memoryCacheEntry{key: key, response: response})
+	c.size += len(response)
+
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*memoryCacheEntry)
+	delete(c.responses, entry.key)
+	c.size -= len(entry.response)
+}
+
+// DiskCache is a Cache that keeps the responses in files of a directory,
+// so that they are kept between runs.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache returns a DiskCache in the given directory, which is created if it does not exist.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create cache directory: %w", err)
+	}
+
+	return &DiskCache{dir: dir}, nil
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	response, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return response, true
+}
+
+func (c *DiskCache) Set(key string, response []byte) error {
+	// The response is written to a temporary file first so that concurrent
+	// readers never see a partially written response.
+	file, err := os.CreateTemp(c.dir, "response-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(response); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), c.path(key))
+}
+
+func (c *DiskCache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+// CacheTransport is an http.RoundTripper that caches the responses to GET requests
+// that have an ETag or a Last-Modified header, and revalidates them with conditional
+// requests. Responses that were not modified (304) do not count against the rate limit.
+// For more information, visit: https://docs.github.com/en/rest/overview/resources-in-the-rest-api#conditional-requests
+type CacheTransport struct {
+	// Base is the transport used to make the requests. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+	// Cache stores the responses.
+	Cache Cache
+	// Identity identifies the credentials of the requests in the cache instead of their
+	// Authorization header, e.g. the installation of a GitHub App, whose tokens expire every hour.
+	Identity string
+	// Logger logs the use of the cached responses. Defaults to DefaultLogger.
+	Logger *Logger
+}
+
+// NewCacheTransport returns a CacheTransport over the given transport that stores the responses in the cache.
+func NewCacheTransport(base http.RoundTripper, cache Cache) *CacheTransport {
+	return &CacheTransport{
+		Base:  base,
+		Cache: cache,
+	}
+}
+
+// RoundTrip sends the request, conditionally when a response to it is cached.
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base().RoundTrip(req)
+	}
+
+	key := cacheKey(req, t.Identity)
+
+	cachedResp := t.getCachedResponse(key, req)
+	if cachedResp != nil {
+		req = req.Clone(req.Context())
+		if etag := cachedResp.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cachedResp.Header.Get("Last-Modified"); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cachedResp != nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		t.logger().Debug("using cached response", requestFields(req))
+
+		return cachedResp, nil
+	}
+
+	if resp.StatusCode != http.StatusOK || (resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") {
+		return resp, nil
+	}
+
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.Cache.Set(key, dump); err != nil {
+		t.logger().Warn("failed to cache response", requestFields(req), Fields{"error": err})
+	}
+
+	return resp, nil
+}
+
+func (t *CacheTransport) logger() *Logger {
+	if t.Logger != nil {
+		return t.Logger
+	}
+	return DefaultLogger()
+}
+
+func (t *CacheTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *CacheTransport) getCachedResponse(key string, req *http.Request) *http.Response {
+	dump, ok := t.Cache.Get(key)
+	if !ok {
+		return nil
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+	if err != nil {
+		t.logger().Warn("ignoring invalid cached response", requestFields(req), Fields{"error": err})
+		return nil
+	}
+
+	return resp
+}
+
+// cacheKey identifies the response to a request.
+// The key includes the identity of the credentials of the request, or the credentials
+// themselves when the identity is not known, since the response depends on what they
+// can access, and is hashed to keep them out of the cache.
+func cacheKey(req *http.Request, identity string) string {
+	if identity == "" {
+		identity = req.Header.Get("Authorization")
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%v\n%v\n%v\n", identity, req.Header.Get("Accept"), req.URL.String())
+	return hex.EncodeToString(hash.Sum(nil))
+}
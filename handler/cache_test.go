@@ -0,0 +1,320 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+// newConditionalServer returns a server that replies with the given validator header
+// and a 304 to the requests that match it, and records the conditional headers of the requests.
+func newConditionalServer(t *testing.T, header, value string) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	requests := make([]string, 0)
+
+	condition := map[string]string{
+		"ETag":          "If-None-Match",
+		"Last-Modified": "If-Modified-Since",
+	}[header]
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, fmt.Sprintf("%v %v %v", r.Method, r.Header.Get("Authorization"), r.Header.Get(condition)))
+		mu.Unlock()
+
+		if condition != "" && r.Header.Get(condition) == value {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if header != "" {
+			w.Header().Set(header, value)
+		}
+		fmt.Fprint(w, `[{"number": 1}]`)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func getBody(t *testing.T, client *http.Client, method, url, token string) (int, string) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func TestCacheTransport(t *testing.T) {
+	tests := map[string]struct {
+		header       string
+		value        string
+		method       string
+		tokens       []string
+		identity     string
+		wantRequests []string
+	}{
+		"etag": {
+			header: "ETag",
+			value:  `"abc"`,
+			method: "GET",
+			tokens: []string{"token", "token"},
+			wantRequests: []string{
+				"GET token ",
+				`GET token "abc"`,
+			},
+		},
+		"last_modified": {
+			header: "Last-Modified",
+			value:  "Thu, 18 Aug 2022 10:00:00 GMT",
+			method: "GET",
+			tokens: []string{"token", "token"},
+			wantRequests: []string{
+				"GET token ",
+				"GET token Thu, 18 Aug 2022 10:00:00 GMT",
+			},
+		},
+		"without_validator": {
+			method: "GET",
+			tokens: []string{"token", "token"},
+			wantRequests: []string{
+				"GET token ",
+				"GET token ",
+			},
+		},
+		"different_token": {
+			header: "ETag",
+			value:  `"abc"`,
+			method: "GET",
+			tokens: []string{"token", "other-token"},
+			wantRequests: []string{
+				"GET token ",
+				"GET other-token ",
+			},
+		},
+		"same_identity": {
+			header:   "ETag",
+			value:    `"abc"`,
+			method:   "GET",
+			tokens:   []string{"token", "other-token"},
+			identity: "installation 7",
+			wantRequests: []string{
+				"GET token ",
+				`GET other-token "abc"`,
+			},
+		},
+		"not_get": {
+			header: "ETag",
+			value:  `"abc"`,
+			method: "POST",
+			tokens: []string{"token", "token"},
+			wantRequests: []string{
+				"POST token ",
+				"POST token ",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server, getRequests := newConditionalServer(t, test.header, test.value)
+
+			transport := handler.NewCacheTransport(nil, handler.NewMemoryCache(0))
+			transport.Identity = test.identity
+
+			client := &http.Client{
+				Transport: transport,
+			}
+
+			for _, token := range test.tokens {
+				status, body := getBody(t, client, test.method, server.URL, token)
+
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, `[{"number": 1}]`, body)
+			}
+
+			assert.Equal(t, test.wantRequests, getRequests())
+		})
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := handler.NewMemoryCache(10)
+
+	assert.Nil(t, cache.Set("a", []byte("aaaa")))
+	assert.Nil(t, cache.Set("b", []byte("bbbb")))
+
+	// Reading "a" makes "b" the least recently used response.
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+
+	assert.Nil(t, cache.Set("c", []byte("cccc")))
+	// Responses larger than the cache are not kept.
+	assert.Nil(t, cache.Set("d", []byte("ddddddddddd")))
+
+	for key, wantOk := range map[string]bool{"a": true, "b": false, "c": true, "d": false} {
+		_, ok := cache.Get(key)
+		assert.Equal(t, wantOk, ok, key)
+	}
+}
+
+func TestCacheTransport_DiskCache(t *testing.T) {
+	server, getRequests := newConditionalServer(t, "ETag", `"abc"`)
+	dir := t.TempDir()
+
+	// Each run of the handler creates a new cache over the same directory.
+	for i := 0; i < 2; i++ {
+		cache, err := handler.NewDiskCache(dir)
+		assert.Nil(t, err)
+
+		client := &http.Client{
+			Transport: handler.NewCacheTransport(nil, cache),
+		}
+
+		status, body := getBody(t, client, "GET", server.URL, "token")
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `[{"number": 1}]`, body)
+	}
+
+	assert.Equal(t, []string{"GET token ", `GET token "abc"`}, getRequests())
+}
+
+func TestCacheTransport_InvalidCachedResponse(t *testing.T) {
+	server, getRequests := newConditionalServer(t, "ETag", `"abc"`)
+	dir := t.TempDir()
+
+	cache, err := handler.NewDiskCache(dir)
+	assert.Nil(t, err)
+
+	client := &http.Client{
+		Transport: handler.NewCacheTransport(nil, &corruptCache{Cache: cache}),
+	}
+
+	for i := 0; i < 2; i++ {
+		status, body := getBody(t, client, "GET", server.URL, "token")
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `[{"number": 1}]`, body)
+	}
+
+	assert.Equal(t, []string{"GET token ", "GET token "}, getRequests())
+}
+
+// corruptCache is a cache whose stored responses can not be read back.
+type corruptCache struct {
+	handler.Cache
+}
+
+func (c *corruptCache) Get(key string) ([]byte, bool) {
+	if _, ok := c.Cache.Get(key); !ok {
+		return nil, false
+	}
+	return []byte("not a response"), true
+}
+
+func TestNewDiskCache_Failure(t *testing.T) {
+	file := writeEventFile(t, "{}")
+
+	gotVal, err := handler.NewDiskCache(file)
+
+	assert.NotNil(t, err)
+	assert.Nil(t, gotVal)
+}
+
+func TestProcessEvent_WithCache(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	conditionalRequests := 0
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/pulls",
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("If-None-Match") == `"pulls"` {
+				conditionalRequests++
+				return httpmock.NewStringResponse(http.StatusNotModified, ""), nil
+			}
+
+			resp := httpmock.NewStringResponse(http.StatusOK, `[{"number": 1, "base": {"repo": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}}]`)
+			resp.Header.Set("ETag", `"pulls"`)
+			return resp, nil
+		},
+	)
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/issues",
+		httpmock.NewStringResponder(http.StatusOK, `[{"number": 1}]`),
+	)
+
+	event := &handler.ActionEvent{
+		EventName:  github.String("schedule"),
+		Token:      github.String("test-token"),
+		Repository: github.String("reviewpad/reviewpad"),
+	}
+
+	cache := handler.NewMemoryCache(0)
+	wantVal := []*handler.TargetEntity{
+		{
+			Kind:   handler.PullRequest,
+			Number: 1,
+			Owner:  "reviewpad",
+			Repo:   "reviewpad",
+			Reason: "cron sweep",
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		gotVal, err := handler.ProcessEvent(event, handler.WithCache(cache))
+
+		assert.Nil(t, err)
+		assert.Equal(t, wantVal, gotVal)
+	}
+
+	assert.Equal(t, 1, conditionalRequests)
+
+	// The rotating tokens of the installation that the events were delivered to share the cache.
+	conditionalRequests = 0
+	cache = handler.NewMemoryCache(0)
+	for _, token := range []string{"ghs_1", "ghs_2"} {
+		installationEvent := &handler.ActionEvent{
+			EventName:    github.String("schedule"),
+			Token:        github.String(token),
+			Repository:   github.String("reviewpad/reviewpad"),
+			EventPayload: buildPayload([]byte(`{"installation": {"id": 7}}`)),
+		}
+
+		gotVal, err := handler.ProcessEvent(installationEvent, handler.WithCache(cache))
+
+		assert.Nil(t, err)
+		assert.Equal(t, wantVal, gotVal)
+	}
+
+	assert.Equal(t, 1, conditionalRequests)
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	reviewpad_gh "github.com/reviewpad/reviewpad/v3/codehost/github"
+	"golang.org/x/oauth2"
+)
+
+// newGithubClient returns a GitHub client authenticated with the token whose requests
+// are made with the transport of newGithubTransport.
+func newGithubClient(ctx context.Context, token string, opts *options) *reviewpad_gh.GithubClient {
+	// The oauth2 client built by reviewpad uses the transport of the client in the context.
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: newGithubTransport(opts),
+	})
+
+	return reviewpad_gh.NewGithubClientFromToken(ctx, token)
+}
+
+// newGithubTransport returns the transport of the requests to the GitHub API, which are
+// retried on rate limits and transient server errors, and cached when a cache is configured.
+// The requests are counted in the metrics when they are configured and traced.
+func newGithubTransport(opts *options) http.RoundTripper {
+	var base http.RoundTripper
+	if opts.metrics != nil {
+		base = &metricsTransport{metrics: opts.metrics}
+	}
+
+	retryTransport := NewRetryTransport(base)
+	retryTransport.Logger = opts.logger
+
+	var transport http.RoundTripper = retryTransport
+	if opts.cache != nil {
+		cacheTransport := NewCacheTransport(transport, opts.cache)
+		cacheTransport.Logger = opts.logger
+		cacheTransport.Identity = opts.cacheIdentity
+		transport = cacheTransport
+	}
+
+	return &tracingTransport{base: transport, tracer: opts.tracer}
+}
@@ -0,0 +1,177 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NewActionEventFromEnv assembles an ActionEvent from the default environment variables
+// set by GitHub Actions and the event payload stored in GITHUB_EVENT_PATH.
+// The token is read from the "token" action input, i.e. INPUT_TOKEN, or from GITHUB_TOKEN.
+// Empty variables are treated as unset, since GitHub Actions sets the inputs that are not
+// provided, and some default variables such as GITHUB_BASE_REF, to an empty value.
+// For more information, visit: https://docs.github.com/en/actions/learn-github-actions/environment-variables#default-environment-variables
+func NewActionEventFromEnv(lookupEnv func(key string) (string, bool)) (*ActionEvent, error) {
+	env := func(key string) *string {
+		if value, ok := lookupEnv(key); ok && value != "" {
+			return &value
+		}
+		return nil
+	}
+
+	event := &ActionEvent{
+		ActionName:       env("GITHUB_ACTION"),
+		ActionPath:       env("GITHUB_ACTION_PATH"),
+		ActionRef:        env("GITHUB_ACTION_REF"),
+		ActionRepository: env("GITHUB_ACTION_REPOSITORY"),
+		Actor:            env("GITHUB_ACTOR"),
+		ApiUrl:           env("GITHUB_API_URL"),
+		BaseRef:          env("GITHUB_BASE_REF"),
+		HeadRef:          env("GITHUB_HEAD_REF"),
+		Env:              env("GITHUB_ENV"),
+		EventName:        env("GITHUB_EVENT_NAME"),
+		EventPath:        env("GITHUB_EVENT_PATH"),
+		QraphqlUrl:       env("GITHUB_GRAPHQL_URL"),
+		JobID:            env("GITHUB_JOB"),
+		Ref:              env("GITHUB_REF"),
+		RefName:          env("GITHUB_REF_NAME"),
+		RefType:          env("GITHUB_REF_TYPE"),
+		Path:             env("GITHUB_PATH"),
+		Repository:       env("GITHUB_REPOSITORY"),
+		RepositoryOwner:  env("GITHUB_REPOSITORY_OWNER"),
+		RetentionDays:    env("GITHUB_RETENTION_DAYS"),
+		RunID:            env("GITHUB_RUN_ID"),
+		RunNumber:        env("GITHUB_RUN_NUMBER"),
+		RunAttempt:       env("GITHUB_RUN_ATTEMPT"),
+		ServerUrl:        env("GITHUB_SERVER_URL"),
+		SHA:              env("GITHUB_SHA"),
+		Token:            env("INPUT_TOKEN"),
+		Workflow:         env("GITHUB_WORKFLOW"),
+		Workspace:        env("GITHUB_WORKSPACE"),
+	}
+
+	if event.Token == nil {
+		event.Token = env("GITHUB_TOKEN")
+	}
+
+	if refProtected := env("GITHUB_REF_PROTECTED"); refProtected != nil {
+		value, err := strconv.ParseBool(*refProtected)
+		if err != nil {
+			return nil, fmt.Errorf("parse GITHUB_REF_PROTECTED: %w", err)
+		}
+		event.RefProtected = &value
+	}
+
+	if event.EventName == nil {
+		return nil, fmt.Errorf("missing GITHUB_EVENT_NAME env variable")
+	}
+
+	if event.EventPath == nil {
+		return nil, fmt.Errorf("missing GITHUB_EVENT_PATH env variable")
+	}
+
+	DefaultLogger().Debug("reading event payload", Fields{"path": *event.EventPath})
+
+	content, err := os.ReadFile(*event.EventPath)
+	if err != nil {
+		return nil, fmt.Errorf("read event payload: %w", err)
+	}
+
+	if !json.Valid(content) {
+		return nil, fmt.Errorf("read event payload: invalid json in %v", *event.EventPath)
+	}
+
+	payload := json.RawMessage(content)
+	event.EventPayload = &payload
+
+	return event, nil
+}
+
+// NewOptionsFromEnv returns the options set with the action inputs:
+//   - allowed_actions and denied_actions, e.g. "pull_request: [opened, synchronize]", see ParseActionList
+//   - bot_logins, a list of logins separated by commas
+//   - deployment_environments and deployment_states, lists separated by commas
+//   - release_max_commits, a number
+//   - linked_entities, include_closed, include_merged and include_locked, booleans that are false by default
+//   - ignore_bots, a boolean that is true by default
+//
+// Inputs that are not provided, which GitHub Actions sets to an empty value, keep their default.
+func NewOptionsFromEnv(lookupEnv func(key string) (string, bool)) ([]Option, error) {
+	input := func(name string) string {
+		value, _ := lookupEnv("INPUT_" + strings.ToUpper(name))
+		return strings.TrimSpace(value)
+	}
+
+	opts := make([]Option, 0)
+
+	for name, withActions := range map[string]func(eventName string, actions ...string) Option{
+		"allowed_actions": WithAllowedActions,
+		"denied_actions":  WithDeniedActions,
+	} {
+		actions, err := ParseActionList(input(name))
+		if err != nil {
+			return nil, fmt.Errorf("parse %v input: %w", name, err)
+		}
+		for eventName, eventActions := range actions {
+			opts = append(opts, withActions(eventName, eventActions...))
+		}
+	}
+
+	if botLogins := SplitList(input("bot_logins")); len(botLogins) > 0 {
+		opts = append(opts, WithBotLogins(botLogins...))
+	}
+
+	if environments := SplitList(input("deployment_environments")); len(environments) > 0 {
+		opts = append(opts, WithDeploymentEnvironments(environments...))
+	}
+
+	if states := SplitList(input("deployment_states")); len(states) > 0 {
+		opts = append(opts, WithDeploymentStates(states...))
+	}
+
+	if value := input("release_max_commits"); value != "" {
+		max, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse release_max_commits input: %w", err)
+		}
+		opts = append(opts, WithReleaseMaxCommits(max))
+	}
+
+	// The option of each flag is set when its value differs from the default.
+	flags := []struct {
+		name         string
+		defaultValue bool
+		option       Option
+	}{
+		{"linked_entities", false, WithLinkedEntities()},
+		{"ignore_bots", true, WithoutBotSenderDetection()},
+		{"include_closed", false, WithClosedEntities()},
+		{"include_merged", false, WithMergedEntities()},
+		{"include_locked", false, WithLockedEntities()},
+	}
+
+	for _, flag := range flags {
+		value := input(flag.name)
+		if value == "" {
+			continue
+		}
+
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse %v input: %w", flag.name, err)
+		}
+
+		if enabled != flag.defaultValue {
+			opts = append(opts, flag.option)
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func writeEventFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewActionEventFromEnv(t *testing.T) {
+	payload := `{"action": "opened", "number": 130}`
+	eventPath := writeEventFile(t, payload)
+
+	tests := map[string]struct {
+		env     map[string]string
+		wantVal *handler.ActionEvent
+	}{
+		"input_token": {
+			env: map[string]string{
+				"GITHUB_ACTION":           "__run",
+				"GITHUB_ACTOR":            "john",
+				"GITHUB_API_URL":          "https://api.github.com",
+				"GITHUB_EVENT_NAME":       "pull_request",
+				"GITHUB_EVENT_PATH":       eventPath,
+				"GITHUB_GRAPHQL_URL":      "https://api.github.com/graphql",
+				"GITHUB_REF":              "refs/pull/130/merge",
+				"GITHUB_REF_PROTECTED":    "false",
+				"GITHUB_REPOSITORY":       "reviewpad/reviewpad",
+				"GITHUB_REPOSITORY_OWNER": "reviewpad",
+				"GITHUB_RUN_ID":           "2885318453",
+				"GITHUB_SHA":              "4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+				"GITHUB_TOKEN":            "github-token",
+				"INPUT_TOKEN":             "input-token",
+			},
+			wantVal: &handler.ActionEvent{
+				ActionName:      github.String("__run"),
+				Actor:           github.String("john"),
+				ApiUrl:          github.String("https://api.github.com"),
+				EventName:       github.String("pull_request"),
+				EventPath:       github.String(eventPath),
+				EventPayload:    buildPayload([]byte(payload)),
+				QraphqlUrl:      github.String("https://api.github.com/graphql"),
+				Ref:             github.String("refs/pull/130/merge"),
+				RefProtected:    github.Bool(false),
+				Repository:      github.String("reviewpad/reviewpad"),
+				RepositoryOwner: github.String("reviewpad"),
+				RunID:           github.String("2885318453"),
+				SHA:             github.String("4bf24cc72f3a62423927a0ac8d70febad7c78e0g"),
+				Token:           github.String("input-token"),
+			},
+		},
+		"github_token": {
+			env: map[string]string{
+				"GITHUB_EVENT_NAME": "pull_request",
+				"GITHUB_EVENT_PATH": eventPath,
+				"GITHUB_TOKEN":      "github-token",
+			},
+			wantVal: &handler.ActionEvent{
+				EventName:    github.String("pull_request"),
+				EventPath:    github.String(eventPath),
+				EventPayload: buildPayload([]byte(payload)),
+				Token:        github.String("github-token"),
+			},
+		},
+		"empty_input_token": {
+			env: map[string]string{
+				"GITHUB_BASE_REF":   "",
+				"GITHUB_EVENT_NAME": "pull_request",
+				"GITHUB_EVENT_PATH": eventPath,
+				"GITHUB_TOKEN":      "github-token",
+				"INPUT_TOKEN":       "",
+			},
+			wantVal: &handler.ActionEvent{
+				EventName:    github.String("pull_request"),
+				EventPath:    github.String(eventPath),
+				EventPayload: buildPayload([]byte(payload)),
+				Token:        github.String("github-token"),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.NewActionEventFromEnv(lookupEnv(test.env))
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantVal, gotVal)
+		})
+	}
+}
+
+func TestNewActionEventFromEnv_Failure(t *testing.T) {
+	eventPath := writeEventFile(t, `{"action": "opened"}`)
+	invalidEventPath := writeEventFile(t, `{,}`)
+
+	tests := map[string]struct {
+		env map[string]string
+	}{
+		"missing_event_name": {
+			env: map[string]string{
+				"GITHUB_EVENT_PATH": eventPath,
+			},
+		},
+		"missing_event_path": {
+			env: map[string]string{
+				"GITHUB_EVENT_NAME": "pull_request",
+			},
+		},
+		"unknown_event_path": {
+			env: map[string]string{
+				"GITHUB_EVENT_NAME": "pull_request",
+				"GITHUB_EVENT_PATH": filepath.Join(t.TempDir(), "unknown.json"),
+			},
+		},
+		"invalid_event_payload": {
+			env: map[string]string{
+				"GITHUB_EVENT_NAME": "pull_request",
+				"GITHUB_EVENT_PATH": invalidEventPath,
+			},
+		},
+		"invalid_ref_protected": {
+			env: map[string]string{
+				"GITHUB_EVENT_NAME":    "pull_request",
+				"GITHUB_EVENT_PATH":    eventPath,
+				"GITHUB_REF_PROTECTED": "maybe",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.NewActionEventFromEnv(lookupEnv(test.env))
+
+			assert.NotNil(t, err)
+			assert.Nil(t, gotVal)
+		})
+	}
+}
+
+func TestNewOptionsFromEnv(t *testing.T) {
+	tests := map[string]struct {
+		env        map[string]string
+		sender     string
+		wantReason string
+	}{
+		"no_inputs": {
+			env:        map[string]string{},
+			sender:     "reviewpad-bot",
+			wantReason: "",
+		},
+		"no_inputs_bot": {
+			env:        map[string]string{},
+			sender:     "renovate[bot]",
+			wantReason: "sender renovate[bot] is a bot",
+		},
+		"ignore_bots_disabled": {
+			env: map[string]string{
+				"INPUT_IGNORE_BOTS": "false",
+			},
+			sender:     "renovate[bot]",
+			wantReason: "",
+		},
+		"denied_actions": {
+			env: map[string]string{
+				"INPUT_DENIED_ACTIONS": "issues: [opened, edited]",
+			},
+			sender:     "reviewpad-bot",
+			wantReason: `action "opened" is denied for 'issues' events`,
+		},
+		"bot_logins": {
+			env: map[string]string{
+				"INPUT_BOT_LOGINS": "renovate, reviewpad-bot",
+			},
+			sender:     "reviewpad-bot",
+			wantReason: "sender reviewpad-bot is a configured bot",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts, err := handler.NewOptionsFromEnv(lookupEnv(test.env))
+			assert.Nil(t, err)
+
+			payload := json.RawMessage(fmt.Sprintf(`{"action": "opened", "sender": {"login": %q}, "issue": {"number": 1, "state": "open"}, "repository": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}`, test.sender))
+			event := &handler.ActionEvent{
+				EventName:    github.String("issues"),
+				EventPayload: &payload,
+			}
+
+			explanation, err := handler.ExplainEvent(event, opts...)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantReason, explanation.SkipReason)
+		})
+	}
+}
+
+func TestNewOptionsFromEnv_Failure(t *testing.T) {
+	tests := map[string]struct {
+		env map[string]string
+	}{
+		"invalid_allowed_actions": {
+			env: map[string]string{
+				"INPUT_ALLOWED_ACTIONS": "opened",
+			},
+		},
+		"invalid_release_max_commits": {
+			env: map[string]string{
+				"INPUT_RELEASE_MAX_COMMITS": "many",
+			},
+		},
+		"invalid_linked_entities": {
+			env: map[string]string{
+				"INPUT_LINKED_ENTITIES": "maybe",
+			},
+		},
+		"invalid_ignore_bots": {
+			env: map[string]string{
+				"INPUT_IGNORE_BOTS": "sometimes",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.NewOptionsFromEnv(lookupEnv(test.env))
+
+			assert.NotNil(t, err)
+			assert.Nil(t, gotVal)
+		})
+	}
+}
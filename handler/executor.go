@@ -0,0 +1,228 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultExecutorWorkers is the number of targets processed at the same time by default.
+const defaultExecutorWorkers = 4
+
+const (
+	// CollectAll processes every target and reports all the failures.
+	CollectAll ErrorMode = iota
+	// FailFast stops processing targets after the first failure.
+	FailFast
+)
+
+// ErrNotExecuted is the error of the targets that were not processed because
+// an earlier target failed in FailFast mode.
+var ErrNotExecuted = errors.New("not processed after an earlier failure")
+
+// ErrorMode selects how an Executor handles the targets that fail.
+type ErrorMode int
+
+// TargetFunc processes one of the targets of an Executor.
+// The context is cancelled when the Executor stops after a failure in FailFast mode.
+type TargetFunc func(ctx context.Context, target *TargetEntity) error
+
+// ExecutionResult is the outcome of processing one of the targets.
+type ExecutionResult struct {
+	Target *TargetEntity
+	// Err is the error of processing the target, if any,
+	// or ErrNotExecuted when the target was not processed.
+	Err error
+}
+
+// ExecutionError aggregates the targets that failed.
+type ExecutionError struct {
+	Targets  int
+	Failures []*ExecutionResult
+}
+
+func (e *ExecutionError) Error() string {
+	failures := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		target := failure.Target
+		failures = append(failures, fmt.Sprintf("%v/%v#%v: %v", target.Owner, target.Repo, target.Number, failure.Err))
+	}
+
+	return fmt.Sprintf("%v of %v targets failed: %v", len(e.Failures), e.Targets, strings.Join(failures, "; "))
+}
+
+// Executor processes targets in parallel, such as the pull requests of a cron sweep,
+// with a bounded number of workers so that it does not trip the secondary rate limits.
+type Executor struct {
+	// Workers is the number of targets processed at the same time.
+	Workers int
+	// RepoWorkers is the number of targets of the same repository processed at the same time.
+	// There is no limit per repository when zero.
+	RepoWorkers int
+	// ErrorMode selects whether the remaining targets are processed after a failure.
+	ErrorMode ErrorMode
+}
+
+// NewExecutor returns an Executor that processes the given number of targets at the same time,
+// or defaultExecutorWorkers when it is not positive, and collects all the failures.
+func NewExecutor(workers int) *Executor {
+	if workers <= 0 {
+		workers = defaultExecutorWorkers
+	}
+
+	return &Executor{
+		Workers:   workers,
+		ErrorMode: CollectAll,
+	}
+}
+
+// Execute processes every target with the function and returns the results in the order of the targets,
+// regardless of the order in which they were processed. When any of the targets failed, the results are
+// returned along with an ExecutionError.
+// The targets are started in order, except that a target waits for a worker of its repository when
+// RepoWorkers are busy while the next targets of other repositories are started.
+func (e *Executor) Execute(ctx context.Context, targets []*TargetEntity, fn TargetFunc) ([]*ExecutionResult, error) {
+	ctx, canc := context.WithCancel(ctx)
+	defer canc()
+
+	results := make([]*ExecutionResult, len(targets))
+	for index, target := range targets {
+		results[index] = &ExecutionResult{Target: target}
+	}
+
+	scheduler := newTargetScheduler(targets, e.RepoWorkers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < e.workers() && i < len(targets); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				index, ok := scheduler.next()
+				if !ok {
+					return
+				}
+
+				err := fn(ctx, targets[index])
+				results[index].Err = err
+
+				abort := err != nil && e.ErrorMode == FailFast
+				if abort {
+					canc()
+				}
+				scheduler.done(index, abort)
+			}
+		}()
+	}
+	wg.Wait()
+
+	failures := make([]*ExecutionResult, 0)
+	for index, result := range results {
+		if !scheduler.started[index] {
+			result.Err = ErrNotExecuted
+			continue
+		}
+
+		if result.Err != nil {
+			failures = append(failures, result)
+		}
+	}
+
+	if len(failures) > 0 {
+		return results, &ExecutionError{Targets: len(targets), Failures: failures}
+	}
+
+	return results, nil
+}
+
+func (e *Executor) workers() int {
+	if e.Workers > 0 {
+		return e.Workers
+	}
+	return defaultExecutorWorkers
+}
+
+// targetScheduler hands out the targets to the workers of an Executor in order,
+// skipping over the targets whose repository is at its limit of workers.
+type targetScheduler struct {
+	targets     []*TargetEntity
+	repoWorkers int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []int
+	started []bool
+	running map[string]int
+	aborted bool
+}
+
+func newTargetScheduler(targets []*TargetEntity, repoWorkers int) *targetScheduler {
+	s := &targetScheduler{
+		targets:     targets,
+		repoWorkers: repoWorkers,
+		pending:     make([]int, 0, len(targets)),
+		started:     make([]bool, len(targets)),
+		running:     make(map[string]int),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	for index := range targets {
+		s.pending = append(s.pending, index)
+	}
+
+	return s
+}
+
+// next returns the index of the next target to process, waiting for a worker of its
+// repository when needed, or false when there are no more targets to process.
+func (s *targetScheduler) next() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if s.aborted || len(s.pending) == 0 {
+			return 0, false
+		}
+
+		for i, index := range s.pending {
+			repo := s.repoKey(index)
+			if s.repoWorkers > 0 && s.running[repo] >= s.repoWorkers {
+				continue
+			}
+
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			s.started[index] = true
+			s.running[repo]++
+			return index, true
+		}
+
+		// Every pending target is waiting for a worker of its repository,
+		// which is released when one of the running targets is done.
+		s.cond.Wait()
+	}
+}
+
+// done releases the worker of the repository of the target.
+// When abort is set, no more targets are handed out.
+func (s *targetScheduler) done(index int, abort bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running[s.repoKey(index)]--
+	if abort {
+		s.aborted = true
+	}
+
+	s.cond.Broadcast()
+}
+
+func (s *targetScheduler) repoKey(index int) string {
+	target := s.targets[index]
+	return fmt.Sprintf("%v/%v", target.Owner, target.Repo)
+}
@@ -0,0 +1,217 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrencyRecorder records the highest number of targets processed at the same time, overall and by repository.
+type concurrencyRecorder struct {
+	mu             sync.Mutex
+	running        int
+	maxRunning     int
+	repoRunning    map[string]int
+	maxRepoRunning map[string]int
+	processed      []int
+}
+
+func newConcurrencyRecorder() *concurrencyRecorder {
+	return &concurrencyRecorder{
+		repoRunning:    make(map[string]int),
+		maxRepoRunning: make(map[string]int),
+	}
+}
+
+func (r *concurrencyRecorder) process(target *handler.TargetEntity, duration time.Duration) {
+	r.mu.Lock()
+	r.running++
+	if r.running > r.maxRunning {
+		r.maxRunning = r.running
+	}
+	r.repoRunning[target.Repo]++
+	if r.repoRunning[target.Repo] > r.maxRepoRunning[target.Repo] {
+		r.maxRepoRunning[target.Repo] = r.repoRunning[target.Repo]
+	}
+	r.processed = append(r.processed, target.Number)
+	r.mu.Unlock()
+
+	time.Sleep(duration)
+
+	r.mu.Lock()
+	r.running--
+	r.repoRunning[target.Repo]--
+	r.mu.Unlock()
+}
+
+func (r *concurrencyRecorder) sortedProcessed() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	processed := append([]int{}, r.processed...)
+	sort.Ints(processed)
+	return processed
+}
+
+func buildTargets(repos ...string) []*handler.TargetEntity {
+	targets := make([]*handler.TargetEntity, 0, len(repos))
+	for i, repo := range repos {
+		targets = append(targets, &handler.TargetEntity{
+			Kind:   handler.PullRequest,
+			Number: i + 1,
+			Owner:  "reviewpad",
+			Repo:   repo,
+		})
+	}
+	return targets
+}
+
+func TestNewExecutor(t *testing.T) {
+	tests := map[string]struct {
+		workers int
+		wantVal *handler.Executor
+	}{
+		"workers": {
+			workers: 8,
+			wantVal: &handler.Executor{Workers: 8, ErrorMode: handler.CollectAll},
+		},
+		"default_workers": {
+			workers: 0,
+			wantVal: &handler.Executor{Workers: 4, ErrorMode: handler.CollectAll},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.wantVal, handler.NewExecutor(test.workers))
+		})
+	}
+}
+
+func TestExecutor_Execute(t *testing.T) {
+	tests := map[string]struct {
+		executor           *handler.Executor
+		repos              []string
+		wantMaxRunning     int
+		wantMaxRepoRunning map[string]int
+	}{
+		"workers": {
+			executor:           handler.NewExecutor(3),
+			repos:              []string{"a", "a", "a", "a", "a", "a", "a", "a"},
+			wantMaxRunning:     3,
+			wantMaxRepoRunning: map[string]int{"a": 3},
+		},
+		"repo_workers": {
+			executor:           &handler.Executor{Workers: 4, RepoWorkers: 1},
+			repos:              []string{"a", "a", "a", "b", "b", "b"},
+			wantMaxRunning:     2,
+			wantMaxRepoRunning: map[string]int{"a": 1, "b": 1},
+		},
+		"more_workers_than_targets": {
+			executor:           handler.NewExecutor(10),
+			repos:              []string{"a", "b"},
+			wantMaxRunning:     2,
+			wantMaxRepoRunning: map[string]int{"a": 1, "b": 1},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := newConcurrencyRecorder()
+			targets := buildTargets(test.repos...)
+
+			gotVal, err := test.executor.Execute(context.Background(), targets, func(ctx context.Context, target *handler.TargetEntity) error {
+				// The first targets take longer, so that they finish out of order.
+				recorder.process(target, time.Millisecond*time.Duration(50-5*target.Number))
+				return nil
+			})
+
+			assert.Nil(t, err)
+			assert.Len(t, gotVal, len(targets))
+			for i, result := range gotVal {
+				assert.Equal(t, targets[i], result.Target)
+				assert.Nil(t, result.Err)
+			}
+			assert.Equal(t, test.wantMaxRunning, recorder.maxRunning)
+			assert.Equal(t, test.wantMaxRepoRunning, recorder.maxRepoRunning)
+		})
+	}
+}
+
+func TestExecutor_Execute_CollectAll(t *testing.T) {
+	targets := buildTargets("a", "a", "a", "a")
+
+	gotVal, err := handler.NewExecutor(2).Execute(context.Background(), targets, func(ctx context.Context, target *handler.TargetEntity) error {
+		if target.Number%2 == 0 {
+			return fmt.Errorf("target %v failed", target.Number)
+		}
+		return nil
+	})
+
+	executionErr, ok := err.(*handler.ExecutionError)
+	assert.True(t, ok)
+	assert.Equal(t, 4, executionErr.Targets)
+	assert.Equal(t, []*handler.ExecutionResult{gotVal[1], gotVal[3]}, executionErr.Failures)
+	assert.EqualError(t, err, "2 of 4 targets failed: reviewpad/a#2: target 2 failed; reviewpad/a#4: target 4 failed")
+
+	assert.Nil(t, gotVal[0].Err)
+	assert.EqualError(t, gotVal[1].Err, "target 2 failed")
+	assert.Nil(t, gotVal[2].Err)
+	assert.EqualError(t, gotVal[3].Err, "target 4 failed")
+}
+
+func TestExecutor_Execute_FailFast(t *testing.T) {
+	targets := buildTargets("a", "a", "a", "a", "a")
+	recorder := newConcurrencyRecorder()
+
+	executor := &handler.Executor{Workers: 2, ErrorMode: handler.FailFast}
+	secondStarted := make(chan struct{})
+
+	gotVal, err := executor.Execute(context.Background(), targets, func(ctx context.Context, target *handler.TargetEntity) error {
+		recorder.process(target, 0)
+
+		switch target.Number {
+		case 1:
+			<-secondStarted
+			return errors.New("target 1 failed")
+		case 2:
+			// The targets that are running when the first target fails are cancelled.
+			close(secondStarted)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		return nil
+	})
+
+	executionErr, ok := err.(*handler.ExecutionError)
+	assert.True(t, ok)
+	assert.Equal(t, 5, executionErr.Targets)
+	assert.EqualError(t, gotVal[0].Err, "target 1 failed")
+	assert.Equal(t, []int{1, 2}, recorder.sortedProcessed())
+	assert.ErrorIs(t, gotVal[1].Err, context.Canceled)
+	for _, result := range gotVal[2:] {
+		assert.Equal(t, handler.ErrNotExecuted, result.Err)
+	}
+	assert.Len(t, executionErr.Failures, 2)
+}
+
+func TestExecutor_Execute_NoTargets(t *testing.T) {
+	gotVal, err := handler.NewExecutor(2).Execute(context.Background(), []*handler.TargetEntity{}, func(ctx context.Context, target *handler.TargetEntity) error {
+		return errors.New("unexpected target")
+	})
+
+	assert.Nil(t, err)
+	assert.Empty(t, gotVal)
+}
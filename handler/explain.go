@@ -0,0 +1,76 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+// Candidate is an entity that was considered as a target of an event.
+type Candidate struct {
+	Target   *TargetEntity
+	Included bool
+	// Reason describes why the candidate was dropped.
+	// The reason why an included candidate was selected is its target's reason.
+	Reason string
+}
+
+// Explanation describes how the targets of an event were selected.
+type Explanation struct {
+	EventName string
+	Action    string
+	// SkipReason is set when the event was skipped without resolving its targets.
+	SkipReason string
+	Candidates []*Candidate
+}
+
+// ExplainEvent processes the event like ProcessEvent and returns every candidate
+// that was considered, whether it was included in the targets and why.
+func ExplainEvent(event *ActionEvent, opts ...Option) (*Explanation, error) {
+	explanation := &Explanation{
+		EventName: *event.EventName,
+		Action:    getEventAction(event),
+	}
+
+	targets, err := ProcessEvent(event, append(opts, withExplanation(explanation))...)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*Candidate, 0, len(targets)+len(explanation.Candidates))
+	for _, target := range targets {
+		candidates = append(candidates, &Candidate{
+			Target:   target,
+			Included: true,
+		})
+	}
+
+	explanation.Candidates = append(candidates, explanation.Candidates...)
+
+	return explanation, nil
+}
+
+func withExplanation(explanation *Explanation) Option {
+	return func(o *options) {
+		o.explanation = explanation
+	}
+}
+
+// skip records why the event is being skipped without resolving its targets.
+func (o *options) skip(reason string) {
+	o.logger.Info("skipping event", Fields{"reason": reason})
+
+	if o.explanation != nil {
+		o.explanation.SkipReason = reason
+	}
+}
+
+// drop records why a candidate is not being included in the targets.
+func (o *options) drop(target *TargetEntity, reason string) {
+	if o.explanation == nil {
+		return
+	}
+
+	o.explanation.Candidates = append(o.explanation.Candidates, &Candidate{
+		Target: target,
+		Reason: reason,
+	})
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainEvent(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	owner := "reviewpad"
+	repo := "reviewpad"
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/pulls", owner, repo),
+		httpmock.NewStringResponder(200, `[
+			{"number": 6, "head": {"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"}, "base": {"repo": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}},
+			{"number": 130, "head": {"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0k"}, "base": {"repo": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}},
+			{"number": 131, "head": {"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"}, "base": {"repo": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}}
+		]`),
+	)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/issues", owner, repo),
+		httpmock.NewStringResponder(200, `[
+			{"number": 130}
+		]`),
+	)
+
+	tests := map[string]struct {
+		event   *handler.ActionEvent
+		opts    []handler.Option
+		wantVal *handler.Explanation
+	}{
+		"status": {
+			event: &handler.ActionEvent{
+				EventName: github.String("status"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"
+				}`)),
+			},
+			wantVal: &handler.Explanation{
+				EventName: "status",
+				Candidates: []*handler.Candidate{
+					{
+						Target: &handler.TargetEntity{
+							Kind:   handler.PullRequest,
+							Number: 130,
+							Owner:  owner,
+							Repo:   repo,
+						},
+						Reason: "head 4bf24cc72f3a62423927a0ac8d70febad7c78e0k does not match SHA 4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+					},
+					{
+						Target: &handler.TargetEntity{
+							Kind:   handler.PullRequest,
+							Number: 131,
+							Owner:  owner,
+							Repo:   repo,
+						},
+						Reason: "head also matches but #6 was selected",
+					},
+					{
+						Target: &handler.TargetEntity{
+							Kind:   handler.PullRequest,
+							Number: 6,
+							Owner:  owner,
+							Repo:   repo,
+							Reason: "head SHA of status event",
+						},
+						Reason: "closed",
+					},
+				},
+			},
+		},
+		"cron": {
+			event: &handler.ActionEvent{
+				EventName:  github.String("schedule"),
+				Token:      github.String("test-token"),
+				Repository: github.String("reviewpad/reviewpad"),
+			},
+			wantVal: &handler.Explanation{
+				EventName: "schedule",
+				Candidates: []*handler.Candidate{
+					{
+						Target: &handler.TargetEntity{
+							Kind:   handler.PullRequest,
+							Number: 130,
+							Owner:  owner,
+							Repo:   repo,
+							Reason: "cron sweep",
+						},
+						Included: true,
+					},
+					{
+						Target: &handler.TargetEntity{
+							Kind:   handler.PullRequest,
+							Number: 6,
+							Owner:  owner,
+							Repo:   repo,
+							Reason: "cron sweep",
+						},
+						Reason: "closed",
+					},
+					{
+						Target: &handler.TargetEntity{
+							Kind:   handler.PullRequest,
+							Number: 131,
+							Owner:  owner,
+							Repo:   repo,
+							Reason: "cron sweep",
+						},
+						Reason: "closed",
+					},
+				},
+			},
+		},
+		"skipped": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "labeled",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"number": 130
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithDeniedActions("pull_request", "labeled"),
+			},
+			wantVal: &handler.Explanation{
+				EventName:  "pull_request",
+				Action:     "labeled",
+				SkipReason: `action "labeled" is denied for 'pull_request' events`,
+				Candidates: []*handler.Candidate{},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.ExplainEvent(test.event, test.opts...)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantVal, gotVal)
+		})
+	}
+}
+
+func TestExplainEvent_Failure(t *testing.T) {
+	event := &handler.ActionEvent{
+		EventName:    github.String("pull_request"),
+		EventPayload: buildPayload([]byte(`{,}`)),
+	}
+
+	gotVal, err := handler.ExplainEvent(event)
+
+	assert.NotNil(t, err)
+	assert.Nil(t, gotVal)
+}
@@ -0,0 +1,175 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidActionList = errors.New("invalid action list")
+
+// ParseActionList parses the actions allowed or denied by event name, for WithAllowedActions
+// and WithDeniedActions, from entries separated by new lines or semicolons, e.g.
+// "pull_request: [opened, synchronize, reopened]; issues: opened".
+func ParseActionList(value string) (map[string][]string, error) {
+	actions := make(map[string][]string)
+
+	entries := strings.FieldsFunc(value, func(r rune) bool {
+		return r == '\n' || r == ';'
+	})
+
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		eventName, list, ok := strings.Cut(entry, ":")
+		eventName = strings.TrimSpace(eventName)
+		if !ok || eventName == "" {
+			return nil, fmt.Errorf("%w: %q is not in the form 'event: action, ...'", ErrInvalidActionList, strings.TrimSpace(entry))
+		}
+
+		list = strings.Trim(strings.TrimSpace(list), "[]")
+		actions[eventName] = append(actions[eventName], SplitList(list)...)
+	}
+
+	return actions, nil
+}
+
+// SplitList splits a list of values separated by commas or new lines, e.g. "dependabot, renovate",
+// and drops the empty values.
+func SplitList(value string) []string {
+	values := make([]string, 0)
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// getEventAction returns the action that triggered the event (e.g. "opened" for 'pull_request' events).
+// Events without an action, such as 'schedule' or 'push', have an empty action.
+func getEventAction(event *ActionEvent) string {
+	if event.EventPayload == nil {
+		return ""
+	}
+
+	payload := struct {
+		Action string `json:"action"`
+	}{}
+
+	if err := json.Unmarshal(*event.EventPayload, &payload); err != nil {
+		return ""
+	}
+
+	return payload.Action
+}
+
+// filterAction reports why the event should be skipped based on the allowed
+// and denied actions configured for its event name.
+// An empty reason means the event should be processed.
+func filterAction(event *ActionEvent, options *options) string {
+	eventName := *event.EventName
+	action := getEventAction(event)
+
+	if allowedActions, ok := options.allowedActions[eventName]; ok && !contains(allowedActions, action) {
+		return fmt.Sprintf("action %q is not allowed for '%v' events", action, eventName)
+	}
+
+	if contains(options.deniedActions[eventName], action) {
+		return fmt.Sprintf("action %q is denied for '%v' events", action, eventName)
+	}
+
+	return ""
+}
+
+// getEventSender returns the login of the user that triggered the event.
+// Events without a sender, such as 'schedule', have an empty sender.
+func getEventSender(event *ActionEvent) string {
+	if event.EventPayload == nil {
+		return ""
+	}
+
+	payload := struct {
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+	}{}
+
+	if err := json.Unmarshal(*event.EventPayload, &payload); err != nil {
+		return ""
+	}
+
+	return payload.Sender.Login
+}
+
+// installationTokenPrefix is the prefix of the installation tokens of GitHub Apps,
+// including the GITHUB_TOKEN of GitHub Actions, which can not fetch their own user.
+// For more information, visit: https://github.blog/2021-04-05-behind-githubs-new-authentication-token-formats/
+const installationTokenPrefix = "ghs_"
+
+func getTokenLogin(token string, opts *options) (string, error) {
+	ctx, canc := context.WithTimeout(opts.ctx, time.Minute*10)
+	defer canc()
+
+	ghClient := newGithubClient(ctx, token, opts)
+
+	user, _, err := ghClient.GetClientREST().Users.Get(ctx, "")
+	if err != nil {
+		return "", err
+	}
+
+	return user.GetLogin(), nil
+}
+
+// filterSender reports why the event should be skipped when it was triggered by
+// reviewpad itself, to prevent the labels and comments added by reviewpad from
+// triggering it over and over again.
+// An empty reason means the event should be processed.
+func filterSender(event *ActionEvent, options *options) string {
+	sender := getEventSender(event)
+	if sender == "" {
+		return ""
+	}
+
+	if contains(options.botLogins, sender) {
+		return fmt.Sprintf("sender %v is a configured bot", sender)
+	}
+
+	if options.botSenderDetection && strings.HasSuffix(sender, "[bot]") {
+		return fmt.Sprintf("sender %v is a bot", sender)
+	}
+
+	if options.tokenIdentityDetection && event.Token != nil && !strings.HasPrefix(*event.Token, installationTokenPrefix) {
+		login, err := getTokenLogin(*event.Token, options)
+		if err != nil {
+			options.logger.Warn("failed to get the user of the token", Fields{"error": err})
+			return ""
+		}
+
+		if login == sender {
+			return fmt.Sprintf("sender %v is the user of the token", sender)
+		}
+	}
+
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
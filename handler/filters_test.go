@@ -0,0 +1,64 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseActionList(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		wantVal map[string][]string
+		wantErr error
+	}{
+		"empty": {
+			value:   "",
+			wantVal: map[string][]string{},
+		},
+		"brackets": {
+			value:   "pull_request: [opened, synchronize, reopened]",
+			wantVal: map[string][]string{"pull_request": {"opened", "synchronize", "reopened"}},
+		},
+		"semicolons": {
+			value: "pull_request: opened,synchronize; issues: opened",
+			wantVal: map[string][]string{
+				"pull_request": {"opened", "synchronize"},
+				"issues":       {"opened"},
+			},
+		},
+		"new_lines": {
+			value: "pull_request: [labeled, edited]\n\nissues: [labeled]\npull_request: [assigned]\n",
+			wantVal: map[string][]string{
+				"pull_request": {"labeled", "edited", "assigned"},
+				"issues":       {"labeled"},
+			},
+		},
+		"missing_event_name": {
+			value:   "opened, synchronize",
+			wantErr: handler.ErrInvalidActionList,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.ParseActionList(test.value)
+
+			assert.True(t, errors.Is(err, test.wantErr))
+			if test.wantErr == nil {
+				assert.Equal(t, test.wantVal, gotVal)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"dependabot[bot]", "renovate[bot]", "reviewpad-bot"}, handler.SplitList(" dependabot[bot], renovate[bot]\nreviewpad-bot,"))
+	assert.Equal(t, []string{}, handler.SplitList(""))
+}
@@ -0,0 +1,268 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	reviewpad_gh "github.com/reviewpad/reviewpad/v3/codehost/github"
+	"github.com/shurcooL/githubv4"
+)
+
+// closingKeywordRegex matches issue references prefixed by one of GitHub's closing keywords,
+// e.g. "Fixes #123" or "Closes org/repo#45".
+// For more information, visit: https://docs.github.com/en/issues/tracking-your-work-with-issues/linking-a-pull-request-to-an-issue#linking-a-pull-request-to-an-issue-using-a-keyword
+var closingKeywordRegex = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+(?:([\w.-]+)/([\w.-]+))?#(\d+)\b`)
+
+type linkedEntity struct {
+	Number     int
+	State      string
+	Locked     bool
+	Repository struct {
+		Name  string
+		Owner struct {
+			Login string
+		}
+	}
+}
+
+type linkedIssuesQuery struct {
+	Repository struct {
+		PullRequest struct {
+			Body                    string
+			ClosingIssuesReferences struct {
+				Nodes []linkedEntity
+			} `graphql:"closingIssuesReferences(first: 50)"`
+		} `graphql:"pullRequest(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+type linkedPullRequestsQuery struct {
+	Repository struct {
+		Issue struct {
+			TimelineItems struct {
+				Nodes []struct {
+					ConnectedEvent struct {
+						Subject struct {
+							PullRequest linkedEntity `graphql:"... on PullRequest"`
+						}
+					} `graphql:"... on ConnectedEvent"`
+					CrossReferencedEvent struct {
+						WillCloseTarget bool
+						Source          struct {
+							PullRequest linkedEntity `graphql:"... on PullRequest"`
+						}
+					} `graphql:"... on CrossReferencedEvent"`
+				}
+			} `graphql:"timelineItems(first: 100, itemTypes: [CONNECTED_EVENT, CROSS_REFERENCED_EVENT])"`
+		} `graphql:"issue(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// parseClosingIssues returns the issues referenced with a closing keyword in the body of a pull request.
+// References without an explicit repository are resolved against the given owner and repo.
+func parseClosingIssues(body, owner, repo string) []*TargetEntity {
+	targets := make([]*TargetEntity, 0)
+	for _, match := range closingKeywordRegex.FindAllStringSubmatch(body, -1) {
+		number, err := strconv.Atoi(match[3])
+		if err != nil {
+			continue
+		}
+
+		target := &TargetEntity{
+			Kind:    Issue,
+			Number:  number,
+			Owner:   owner,
+			Repo:    repo,
+			Derived: true,
+		}
+
+		if match[1] != "" {
+			target.Owner = match[1]
+			target.Repo = match[2]
+		}
+
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+func getLinkedIssues(ctx context.Context, ghClient *reviewpad_gh.GithubClient, pr *TargetEntity, opts *options) ([]*TargetEntity, error) {
+	var query linkedIssuesQuery
+	err := ghClient.GetClientGraphQL().Query(ctx, &query, map[string]interface{}{
+		"owner":  githubv4.String(pr.Owner),
+		"name":   githubv4.String(pr.Repo),
+		"number": githubv4.Int(pr.Number),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	targets := parseClosingIssues(query.Repository.PullRequest.Body, pr.Owner, pr.Repo)
+	for _, issue := range query.Repository.PullRequest.ClosingIssuesReferences.Nodes {
+		target := &TargetEntity{
+			Kind:    Issue,
+			Number:  issue.Number,
+			Owner:   issue.Repository.Owner.Login,
+			Repo:    issue.Repository.Name,
+			Derived: true,
+		}
+
+		opts.setState(target, issue.State, issue.Locked, false)
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+func getLinkedPullRequests(ctx context.Context, ghClient *reviewpad_gh.GithubClient, issue *TargetEntity, opts *options) ([]*TargetEntity, error) {
+	var query linkedPullRequestsQuery
+	err := ghClient.GetClientGraphQL().Query(ctx, &query, map[string]interface{}{
+		"owner":  githubv4.String(issue.Owner),
+		"name":   githubv4.String(issue.Repo),
+		"number": githubv4.Int(issue.Number),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*TargetEntity, 0)
+	for _, node := range query.Repository.Issue.TimelineItems.Nodes {
+		pr := node.ConnectedEvent.Subject.PullRequest
+		if node.CrossReferencedEvent.WillCloseTarget {
+			pr = node.CrossReferencedEvent.Source.PullRequest
+		}
+
+		// Timeline items that do not link a pull request, such as
+		// cross references from other issues, have no number.
+		if pr.Number == 0 {
+			continue
+		}
+
+		target := &TargetEntity{
+			Kind:    PullRequest,
+			Number:  pr.Number,
+			Owner:   pr.Repository.Owner.Login,
+			Repo:    pr.Repository.Name,
+			Derived: true,
+		}
+
+		opts.setState(target, pr.State, pr.Locked, false)
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// getPayloadPullRequestIssue returns the key of the issue of the event payload when it is a pull request.
+// Comments on pull requests are delivered as 'issue_comment' events, where the pull request is an issue
+// with a link to the pull request, which GitHub does not resolve when queried as an issue.
+func getPayloadPullRequestIssue(event *ActionEvent) string {
+	if event.EventPayload == nil {
+		return ""
+	}
+
+	payload := struct {
+		Repository struct {
+			Name  string `json:"name"`
+			Owner struct {
+				Login string `json:"login"`
+			} `json:"owner"`
+		} `json:"repository"`
+		Issue *payloadEntity `json:"issue"`
+	}{}
+
+	if err := json.Unmarshal(*event.EventPayload, &payload); err != nil || payload.Issue == nil || payload.Issue.PullRequest == nil {
+		return ""
+	}
+
+	return targetKey(&TargetEntity{
+		Kind:   Issue,
+		Number: payload.Issue.Number,
+		Owner:  payload.Repository.Owner.Login,
+		Repo:   payload.Repository.Name,
+	})
+}
+
+// expandLinkedEntities adds to the targets the issues closed by the pull request targets
+// and the pull requests that close the issue targets.
+// Only targets that are the subject of the event are expanded.
+func expandLinkedEntities(event *ActionEvent, targets []*TargetEntity, opts *options) ([]*TargetEntity, error) {
+	// The pull request events are processed without a token, but the links are only available with one.
+	if event.Token == nil {
+		opts.logger.Warn("skipping linked entities", Fields{"reason": "missing token"})
+		return targets, nil
+	}
+
+	ctx, canc := context.WithTimeout(opts.ctx, time.Minute*10)
+	defer canc()
+
+	ghClient := newGithubClient(ctx, *event.Token, opts)
+	pullRequestIssue := getPayloadPullRequestIssue(event)
+
+	found := make(map[string]bool)
+	for _, target := range targets {
+		found[targetKey(target)] = true
+	}
+
+	expandedTargets := targets
+	for _, target := range targets {
+		if target.Derived {
+			continue
+		}
+
+		var linkedTargets []*TargetEntity
+		var reason string
+		var err error
+
+		kind := target.Kind
+		if kind == Issue && targetKey(target) == pullRequestIssue {
+			kind = PullRequest
+		}
+
+		switch kind {
+		case PullRequest:
+			opts.logger.Debug("looking for linked issues", targetFields(target))
+			reason = fmt.Sprintf("linked issue of #%v", target.Number)
+			linkedTargets, err = getLinkedIssues(ctx, ghClient, target, opts)
+			if err != nil {
+				return nil, fmt.Errorf("get linked issues: %w", err)
+			}
+		case Issue:
+			opts.logger.Debug("looking for linked pull requests", targetFields(target))
+			reason = fmt.Sprintf("linked pull request of #%v", target.Number)
+			linkedTargets, err = getLinkedPullRequests(ctx, ghClient, target, opts)
+			if err != nil {
+				return nil, fmt.Errorf("get linked pull requests: %w", err)
+			}
+		}
+
+		for _, linkedTarget := range linkedTargets {
+			linkedTarget.Reason = reason
+
+			key := targetKey(linkedTarget)
+			if found[key] {
+				opts.drop(linkedTarget, "already a target")
+				continue
+			}
+
+			opts.logger.Info("found linked target", targetFields(linkedTarget))
+
+			found[key] = true
+			expandedTargets = append(expandedTargets, linkedTarget)
+		}
+	}
+
+	return expandedTargets, nil
+}
+
+func targetKey(target *TargetEntity) string {
+	return fmt.Sprintf("%v/%v/%v#%v", target.Owner, target.Repo, target.Kind, target.Number)
+}
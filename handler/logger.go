@@ -0,0 +1,345 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var (
+	ErrUnknownLogLevel  = errors.New("unknown log level")
+	ErrUnknownLogFormat = errors.New("unknown log format")
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("unknown(%d)", int(level))
+}
+
+// ParseLevel returns the level with the given name (e.g. "debug").
+func ParseLevel(name string) (Level, error) {
+	for _, level := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		if strings.EqualFold(name, level.String()) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownLogLevel, name)
+}
+
+// Fields are the key-value pairs that describe a log entry, e.g. the event name or the repository.
+type Fields map[string]interface{}
+
+// Entry is a log entry.
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  Fields
+}
+
+// Encoder encodes a log entry into a line of output.
+type Encoder interface {
+	Encode(entry *Entry) ([]byte, error)
+}
+
+// ParseEncoder returns the encoder of the format with the given name: "text", "json" or "actions".
+func ParseEncoder(name string) (Encoder, error) {
+	switch strings.ToLower(name) {
+	case "text":
+		return TextEncoder{}, nil
+	case "json":
+		return JSONEncoder{}, nil
+	case "actions":
+		return ActionsEncoder{}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownLogFormat, name)
+}
+
+// TextEncoder encodes the entries as logfmt lines, e.g.
+// time=2022-08-18T10:00:00Z level=info msg="found pull request" number=1
+type TextEncoder struct{}
+
+func (TextEncoder) Encode(entry *Entry) ([]byte, error) {
+	var line strings.Builder
+
+	fmt.Fprintf(&line, "time=%v level=%v msg=%v", entry.Time.Format(time.RFC3339), entry.Level, formatValue(entry.Message))
+	if fields := formatFields(entry.Fields); fields != "" {
+		line.WriteString(" " + fields)
+	}
+	line.WriteString("\n")
+
+	return []byte(line.String()), nil
+}
+
+// JSONEncoder encodes the entries as JSON objects, one per line.
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(entry *Entry) ([]byte, error) {
+	object := make(map[string]interface{}, len(entry.Fields)+3)
+	for key, value := range entry.Fields {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		object[key] = value
+	}
+
+	object["time"] = entry.Time.Format(time.RFC3339)
+	object["level"] = entry.Level.String()
+	object["msg"] = entry.Message
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// ActionsEncoder encodes the entries as GitHub Actions workflow commands, so that the debug entries
+// are only shown when debug logging is enabled and the warnings and errors are annotated in the run.
+// For more information, visit: https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+type ActionsEncoder struct{}
+
+func (ActionsEncoder) Encode(entry *Entry) ([]byte, error) {
+	message := entry.Message
+	if fields := formatFields(entry.Fields); fields != "" {
+		message += " " + fields
+	}
+
+	command := map[Level]string{
+		LevelDebug: "debug",
+		LevelWarn:  "warning",
+		LevelError: "error",
+	}[entry.Level]
+
+	if command == "" {
+		return []byte(message + "\n"), nil
+	}
+
+	// The data of a workflow command ends at the end of the line.
+	escaper := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+	return []byte(fmt.Sprintf("::%v::%v\n", command, escaper.Replace(message))), nil
+}
+
+// formatFields formats the fields as key=value pairs sorted by key.
+func formatFields(fields Fields) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", key, formatValue(fields[key])))
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// formatValue formats the value of a field, quoted when it is empty or has spaces, quotes or equal signs.
+func formatValue(value interface{}) string {
+	text := fmt.Sprintf("%v", value)
+	if text == "" || strings.ContainsAny(text, " \t\r\n\"=") {
+		return strconv.Quote(text)
+	}
+	return text
+}
+
+// Logger writes leveled, structured log entries.
+// The loggers derived with WithFields share the output of the logger they are derived from.
+type Logger struct {
+	out     io.Writer
+	mu      *sync.Mutex
+	level   Level
+	encoder Encoder
+	fields  Fields
+}
+
+// NewLogger returns a Logger that writes the entries of the given level and above to out with the encoder.
+func NewLogger(out io.Writer, level Level, encoder Encoder) *Logger {
+	return &Logger{
+		out:     out,
+		mu:      &sync.Mutex{},
+		level:   level,
+		encoder: encoder,
+		fields:  Fields{},
+	}
+}
+
+// NewLoggerFromEnv returns the Logger for the environment the handler is running in.
+// In GitHub Actions, it writes workflow commands to stdout, including the debug entries,
+// which are only shown when debug logging is enabled. Otherwise, it writes text to stderr.
+func NewLoggerFromEnv(lookupEnv func(key string) (string, bool)) *Logger {
+	if actions, _ := lookupEnv("GITHUB_ACTIONS"); actions == "true" {
+		return NewLogger(os.Stdout, LevelDebug, ActionsEncoder{})
+	}
+
+	return NewLogger(os.Stderr, LevelInfo, TextEncoder{})
+}
+
+var (
+	defaultLoggerMu sync.RWMutex
+	defaultLogger   = NewLoggerFromEnv(os.LookupEnv)
+)
+
+// DefaultLogger returns the Logger used when none is set with WithLogger,
+// and by the functions that do not take options, such as ParseEvent.
+func DefaultLogger() *Logger {
+	defaultLoggerMu.RLock()
+	defer defaultLoggerMu.RUnlock()
+
+	return defaultLogger
+}
+
+// SetDefaultLogger replaces the Logger returned by DefaultLogger.
+func SetDefaultLogger(logger *Logger) {
+	defaultLoggerMu.Lock()
+	defer defaultLoggerMu.Unlock()
+
+	defaultLogger = logger
+}
+
+// WithFields returns a Logger that adds the fields to every entry, on top of the fields of this logger.
+func (l *Logger) WithFields(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	return &Logger{
+		out:     l.out,
+		mu:      l.mu,
+		level:   l.level,
+		encoder: l.encoder,
+		fields:  merged,
+	}
+}
+
+func (l *Logger) Debug(message string, fields ...Fields) {
+	l.log(LevelDebug, message, fields)
+}
+
+func (l *Logger) Info(message string, fields ...Fields) {
+	l.log(LevelInfo, message, fields)
+}
+
+func (l *Logger) Warn(message string, fields ...Fields) {
+	l.log(LevelWarn, message, fields)
+}
+
+func (l *Logger) Error(message string, fields ...Fields) {
+	l.log(LevelError, message, fields)
+}
+
+func (l *Logger) log(level Level, message string, fields []Fields) {
+	if level < l.level {
+		return
+	}
+
+	entry := &Entry{
+		Time:    time.Now().UTC(),
+		Level:   level,
+		Message: message,
+		Fields:  l.fields,
+	}
+
+	if len(fields) > 0 {
+		entry.Fields = l.WithFields(mergeFields(fields)).fields
+	}
+
+	line, err := l.encoder.Encode(entry)
+	if err != nil {
+		line = []byte(fmt.Sprintf("failed to encode log entry %q: %v\n", message, err))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.out.Write(line)
+}
+
+// eventFields are the fields that identify the event in the log entries.
+func eventFields(event *ActionEvent) Fields {
+	fields := Fields{}
+
+	if event.EventName != nil {
+		fields["event_name"] = *event.EventName
+	}
+
+	if event.DeliveryID != nil {
+		fields["delivery_id"] = *event.DeliveryID
+	}
+
+	if event.Repository != nil {
+		if repoParts := strings.SplitN(*event.Repository, "/", 2); len(repoParts) == 2 {
+			fields["owner"] = repoParts[0]
+			fields["repo"] = repoParts[1]
+		}
+	}
+
+	return fields
+}
+
+// targetFields are the fields that identify the target in the log entries.
+func targetFields(target *TargetEntity) Fields {
+	return Fields{
+		"kind":   target.Kind,
+		"owner":  target.Owner,
+		"repo":   target.Repo,
+		"number": target.Number,
+	}
+}
+
+// requestFields are the fields that identify a request to the GitHub API in the log entries.
+func requestFields(req *http.Request) Fields {
+	return Fields{
+		"method": req.Method,
+		"path":   req.URL.Path,
+	}
+}
+
+func mergeFields(fields []Fields) Fields {
+	merged := make(Fields)
+	for _, f := range fields {
+		for key, value := range f {
+			merged[key] = value
+		}
+	}
+	return merged
+}
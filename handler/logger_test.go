@@ -0,0 +1,238 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeLogEntries decodes the entries written by a logger with the JSON encoder, without their time.
+func decodeLogEntries(t *testing.T, output string) []map[string]interface{} {
+	entries := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.NotEmpty(t, entry["time"])
+		delete(entry, "time")
+
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]struct {
+		name    string
+		wantVal handler.Level
+		wantErr error
+	}{
+		"debug":   {name: "debug", wantVal: handler.LevelDebug},
+		"info":    {name: "info", wantVal: handler.LevelInfo},
+		"warn":    {name: "WARN", wantVal: handler.LevelWarn},
+		"error":   {name: "error", wantVal: handler.LevelError},
+		"unknown": {name: "trace", wantErr: handler.ErrUnknownLogLevel},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.ParseLevel(test.name)
+
+			assert.True(t, errors.Is(err, test.wantErr))
+			assert.Equal(t, test.wantVal, gotVal)
+		})
+	}
+}
+
+func TestLevel_String(t *testing.T) {
+	assert.Equal(t, "warn", handler.LevelWarn.String())
+	assert.Equal(t, "unknown(7)", handler.Level(7).String())
+}
+
+func TestParseEncoder(t *testing.T) {
+	tests := map[string]struct {
+		name    string
+		wantVal handler.Encoder
+		wantErr error
+	}{
+		"text":    {name: "text", wantVal: handler.TextEncoder{}},
+		"json":    {name: "JSON", wantVal: handler.JSONEncoder{}},
+		"actions": {name: "actions", wantVal: handler.ActionsEncoder{}},
+		"unknown": {name: "xml", wantErr: handler.ErrUnknownLogFormat},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.ParseEncoder(test.name)
+
+			assert.True(t, errors.Is(err, test.wantErr))
+			assert.Equal(t, test.wantVal, gotVal)
+		})
+	}
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	entry := &handler.Entry{
+		Time:    time.Date(2022, 8, 18, 10, 0, 0, 0, time.UTC),
+		Level:   handler.LevelInfo,
+		Message: "found pull request",
+		Fields: handler.Fields{
+			"event_name": "pull_request",
+			"number":     1,
+			"reason":     "head SHA of status event",
+			"error":      errors.New("not found"),
+		},
+	}
+
+	tests := map[string]struct {
+		encoder handler.Encoder
+		entry   *handler.Entry
+		wantVal string
+	}{
+		"text": {
+			encoder: handler.TextEncoder{},
+			entry:   entry,
+			wantVal: `time=2022-08-18T10:00:00Z level=info msg="found pull request" error="not found" event_name=pull_request number=1 reason="head SHA of status event"` + "\n",
+		},
+		"text_without_fields": {
+			encoder: handler.TextEncoder{},
+			entry:   &handler.Entry{Time: entry.Time, Level: handler.LevelDebug, Message: "parsing"},
+			wantVal: "time=2022-08-18T10:00:00Z level=debug msg=parsing\n",
+		},
+		"json": {
+			encoder: handler.JSONEncoder{},
+			entry:   entry,
+			wantVal: `{"error":"not found","event_name":"pull_request","level":"info","msg":"found pull request","number":1,"reason":"head SHA of status event","time":"2022-08-18T10:00:00Z"}` + "\n",
+		},
+		"actions_info": {
+			encoder: handler.ActionsEncoder{},
+			entry:   entry,
+			wantVal: `found pull request error="not found" event_name=pull_request number=1 reason="head SHA of status event"` + "\n",
+		},
+		"actions_debug": {
+			encoder: handler.ActionsEncoder{},
+			entry:   &handler.Entry{Level: handler.LevelDebug, Message: "parsing event", Fields: handler.Fields{"progress": "50%"}},
+			wantVal: "::debug::parsing event progress=50%25\n",
+		},
+		"actions_warning": {
+			encoder: handler.ActionsEncoder{},
+			entry:   &handler.Entry{Level: handler.LevelWarn, Message: "low rate limit quota"},
+			wantVal: "::warning::low rate limit quota\n",
+		},
+		"actions_error": {
+			encoder: handler.ActionsEncoder{},
+			entry:   &handler.Entry{Level: handler.LevelError, Message: "failed", Fields: handler.Fields{"error": "first\nsecond"}},
+			wantVal: "::error::failed error=\"first\\nsecond\"\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := test.encoder.Encode(test.entry)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantVal, string(gotVal))
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := handler.NewLogger(&buf, handler.LevelInfo, handler.JSONEncoder{})
+
+	eventLogger := logger.WithFields(handler.Fields{"event_name": "issues", "owner": "reviewpad"})
+
+	eventLogger.Debug("processing 'issues' event")
+	eventLogger.Info("found issue", handler.Fields{"number": 1}, handler.Fields{"owner": "explore-dev"})
+	eventLogger.Warn("low rate limit quota")
+	logger.Error("failed")
+
+	assert.Equal(t, []map[string]interface{}{
+		{"level": "info", "msg": "found issue", "event_name": "issues", "owner": "explore-dev", "number": float64(1)},
+		{"level": "warn", "msg": "low rate limit quota", "event_name": "issues", "owner": "reviewpad"},
+		{"level": "error", "msg": "failed"},
+	}, decodeLogEntries(t, buf.String()))
+}
+
+func TestSetDefaultLogger(t *testing.T) {
+	defaultLogger := handler.DefaultLogger()
+	defer handler.SetDefaultLogger(defaultLogger)
+
+	var buf bytes.Buffer
+	handler.SetDefaultLogger(handler.NewLogger(&buf, handler.LevelDebug, handler.JSONEncoder{}))
+
+	_, err := handler.ParseEvent(`{"event_name": "issues"}`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"level": "debug", "msg": "parsing event"},
+	}, decodeLogEntries(t, buf.String()))
+}
+
+func TestProcessEvent_WithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := handler.NewLogger(&buf, handler.LevelInfo, handler.JSONEncoder{})
+
+	event := &handler.ActionEvent{
+		EventName:  github.String("issues"),
+		DeliveryID: github.String("72d3162e-cc78-11e3-81ab-4c9367dc0958"),
+		Repository: github.String("reviewpad/reviewpad"),
+		Token:      github.String("test-token"),
+		EventPayload: buildPayload([]byte(`{
+			"action": "opened",
+			"repository": {
+				"name": "reviewpad",
+				"owner": {
+					"login": "reviewpad"
+				}
+			},
+			"issue": {
+				"number": 131,
+				"state": "closed"
+			}
+		}`)),
+	}
+
+	_, err := handler.ProcessEvent(event, handler.WithLogger(logger))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"level":       "info",
+			"msg":         "found issue",
+			"event_name":  "issues",
+			"delivery_id": "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+			"owner":       "reviewpad",
+			"repo":        "reviewpad",
+			"number":      float64(131),
+		},
+		{
+			"level":       "info",
+			"msg":         "skipping target",
+			"event_name":  "issues",
+			"delivery_id": "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+			"kind":        "issue",
+			"owner":       "reviewpad",
+			"repo":        "reviewpad",
+			"number":      float64(131),
+			"reason":      "closed",
+		},
+	}, decodeLogEntries(t, buf.String()))
+}
@@ -0,0 +1,164 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "host_event_handler"
+
+// Metrics collects the metrics of the handler in a Prometheus registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	eventsReceived     *prometheus.CounterVec
+	targetsEmitted     *prometheus.CounterVec
+	processingErrors   *prometheus.CounterVec
+	processingDuration *prometheus.HistogramVec
+	githubRequests     *prometheus.CounterVec
+	rateLimitRemaining *prometheus.GaugeVec
+}
+
+// NewMetrics returns Metrics registered in a new registry.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		eventsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "events_received_total",
+			Help:      "Number of events received by event name and action.",
+		}, []string{"event_name", "action"}),
+		targetsEmitted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "targets_emitted_total",
+			Help:      "Number of targets emitted by kind.",
+		}, []string{"kind"}),
+		processingErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "processing_errors_total",
+			Help:      "Number of events that failed to be processed by type of error.",
+		}, []string{"type"}),
+		processingDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "process_event_duration_seconds",
+			Help:      "Time taken to process an event by event name.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+		}, []string{"event_name"}),
+		githubRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "github_requests_total",
+			Help:      "Number of requests to the GitHub API by method and status code.",
+		}, []string{"method", "code"}),
+		rateLimitRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "github_rate_limit_remaining",
+			Help:      "Number of requests remaining in the current rate limit window by resource.",
+		}, []string{"resource"}),
+	}
+
+	m.registry.MustRegister(
+		m.eventsReceived,
+		m.targetsEmitted,
+		m.processingErrors,
+		m.processingDuration,
+		m.githubRequests,
+		m.rateLimitRemaining,
+	)
+
+	return m
+}
+
+// Handler returns the handler of the /metrics endpoint, in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry returns the registry of the metrics, e.g. to register the metrics of the process.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// observeEvent records an event processed by ProcessEvent.
+func (m *Metrics) observeEvent(eventName, action string, seconds float64, targets []*TargetEntity, err error) {
+	m.eventsReceived.WithLabelValues(eventName, action).Inc()
+	m.processingDuration.WithLabelValues(eventName).Observe(seconds)
+
+	if err != nil {
+		m.processingErrors.WithLabelValues(errorType(err)).Inc()
+		return
+	}
+
+	for _, target := range targets {
+		m.targetsEmitted.WithLabelValues(string(target.Kind)).Inc()
+	}
+}
+
+// errorType classifies the errors of ProcessEvent for the processing errors metric.
+func errorType(err error) string {
+	var rateLimitErr *github.RateLimitError
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	var responseErr *github.ErrorResponse
+	var syntaxErr *json.SyntaxError
+	var unmarshalTypeErr *json.UnmarshalTypeError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &rateLimitErr), errors.As(err, &abuseRateLimitErr):
+		return "rate_limit"
+	case errors.As(err, &responseErr):
+		return "github_api"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &netErr):
+		return "network"
+	case errors.As(err, &syntaxErr), errors.As(err, &unmarshalTypeErr):
+		return "parse"
+	}
+
+	return "other"
+}
+
+// metricsTransport is an http.RoundTripper that counts the requests to the GitHub API
+// and records the remaining rate limit of their responses.
+// Each attempt of a retried request is counted.
+type metricsTransport struct {
+	base    http.RoundTripper
+	metrics *Metrics
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		t.metrics.githubRequests.WithLabelValues(req.Method, "error").Inc()
+		return nil, err
+	}
+
+	t.metrics.githubRequests.WithLabelValues(req.Method, strconv.Itoa(resp.StatusCode)).Inc()
+
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		resource := resp.Header.Get("X-RateLimit-Resource")
+		if resource == "" {
+			resource = "core"
+		}
+		t.metrics.rateLimitRemaining.WithLabelValues(resource).Set(float64(remaining))
+	}
+
+	return resp, nil
+}
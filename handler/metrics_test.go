@@ -0,0 +1,132 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+// scrapeMetrics returns the metrics exposed by the handler, without the comments.
+// The request bypasses httpmock, which replaces the default transport.
+func scrapeMetrics(t *testing.T, handler http.Handler) []string {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{}}
+
+	resp, err := client.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	metrics := make([]string, 0)
+	for _, line := range strings.Split(string(body), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			metrics = append(metrics, line)
+		}
+	}
+	return metrics
+}
+
+// registerRateLimitedResponder registers a responder with the given body and remaining rate limit.
+func registerRateLimitedResponder(method, url, body string, remaining int) {
+	httpmock.RegisterResponder(method, url, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, body)
+		resp.Header.Set("X-RateLimit-Remaining", fmt.Sprint(remaining))
+		resp.Header.Set("X-RateLimit-Resource", "core")
+		return resp, nil
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerRateLimitedResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/pulls",
+		`[{"number": 6, "head": {"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"}, "base": {"repo": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}}]`,
+		4999,
+	)
+	registerRateLimitedResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/issues",
+		`[{"number": 6, "pull_request": {}}]`,
+		4998,
+	)
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/other/pulls",
+		httpmock.NewStringResponder(404, `{"message": "Not Found"}`),
+	)
+
+	metrics := handler.NewMetrics()
+
+	targets, err := handler.ProcessEvent(statusEvent("4bf24cc72f3a62423927a0ac8d70febad7c78e0g"), handler.WithMetrics(metrics))
+	assert.Nil(t, err)
+	assert.Len(t, targets, 1)
+
+	issuesEvent := &handler.ActionEvent{
+		EventName: github.String("issues"),
+		Token:     github.String("test-token"),
+		EventPayload: buildPayload([]byte(`{
+			"action": "opened",
+			"repository": {
+				"name": "reviewpad",
+				"owner": {
+					"login": "reviewpad"
+				}
+			},
+			"issue": {
+				"number": 130,
+				"state": "open"
+			}
+		}`)),
+	}
+	_, err = handler.ProcessEvent(issuesEvent, handler.WithMetrics(metrics))
+	assert.Nil(t, err)
+
+	failingEvent := statusEvent("4bf24cc72f3a62423927a0ac8d70febad7c78e0g")
+	failingEvent.EventPayload = buildPayload([]byte(`{
+		"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+		"repository": {
+			"name": "other",
+			"owner": {
+				"login": "reviewpad"
+			}
+		}
+	}`))
+	_, err = handler.ProcessEvent(failingEvent, handler.WithMetrics(metrics))
+	assert.NotNil(t, err)
+
+	gotVal := scrapeMetrics(t, metrics.Handler())
+
+	for _, wantMetric := range []string{
+		`host_event_handler_events_received_total{action="",event_name="status"} 2`,
+		`host_event_handler_events_received_total{action="opened",event_name="issues"} 1`,
+		`host_event_handler_targets_emitted_total{kind="pull_request"} 1`,
+		`host_event_handler_targets_emitted_total{kind="issue"} 1`,
+		`host_event_handler_processing_errors_total{type="github_api"} 1`,
+		`host_event_handler_github_requests_total{code="200",method="GET"} 2`,
+		`host_event_handler_github_requests_total{code="404",method="GET"} 1`,
+		`host_event_handler_github_rate_limit_remaining{resource="core"} 4998`,
+		`host_event_handler_process_event_duration_seconds_count{event_name="status"} 2`,
+		`host_event_handler_process_event_duration_seconds_count{event_name="issues"} 1`,
+	} {
+		assert.Contains(t, gotVal, wantMetric)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option configures how ProcessEvent resolves the targets of an event.
+type Option func(*options)
+
+// defaultReleaseMaxCommits matches the number of commits the compare API
+// returns in a single response.
+const defaultReleaseMaxCommits = 250
+
+type options struct {
+	deploymentEnvironments []string
+	deploymentStates       []string
+	releaseMaxCommits      int
+	linkedEntities         bool
+	allowedActions         map[string][]string
+	deniedActions          map[string][]string
+	botLogins              []string
+	botSenderDetection     bool
+	tokenIdentityDetection bool
+	includeClosed          bool
+	includeMerged          bool
+	includeLocked          bool
+	states                 map[string]*entityState
+	cache                  Cache
+	cacheIdentity          string
+	shaResolver            *ShaResolver
+	graphqlURL             string
+	logger                 *Logger
+	metrics                *Metrics
+	tracer                 trace.Tracer
+	ctx                    context.Context
+	explanation            *Explanation
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		releaseMaxCommits:      defaultReleaseMaxCommits,
+		allowedActions:         make(map[string][]string),
+		deniedActions:          make(map[string][]string),
+		states:                 make(map[string]*entityState),
+		botSenderDetection:     true,
+		tokenIdentityDetection: true,
+		graphqlURL:             defaultGraphqlURL,
+		logger:                 DefaultLogger(),
+		tracer:                 otel.GetTracerProvider().Tracer(tracerName),
+		ctx:                    context.Background(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithDeploymentEnvironments restricts 'deployment' and 'deployment_status' events
+// to the given environment names (e.g. "staging").
+// By default, deployments to any environment are processed.
+func WithDeploymentEnvironments(environments ...string) Option {
+	return func(o *options) {
+		o.deploymentEnvironments = append(o.deploymentEnvironments, environments...)
+	}
+}
+
+// WithDeploymentStates restricts 'deployment_status' events to the given
+// deployment states (e.g. "success").
+// By default, deployment statuses in any state are processed.
+func WithDeploymentStates(states ...string) Option {
+	return func(o *options) {
+		o.deploymentStates = append(o.deploymentStates, states...)
+	}
+}
+
+// WithReleaseMaxCommits caps the number of commits between two releases that are
+// mapped to their pull requests when processing 'release' events.
+// Values below 1 keep the default cap of 250 commits.
+func WithReleaseMaxCommits(max int) Option {
+	return func(o *options) {
+		if max > 0 {
+			o.releaseMaxCommits = max
+		}
+	}
+}
+
+// WithLinkedEntities expands pull request targets to the issues they close,
+// either through a closing keyword in their description (e.g. "Fixes #123")
+// or through GitHub's linked issues, and issue targets to the pull requests
+// that close them.
+func WithLinkedEntities() Option {
+	return func(o *options) {
+		o.linkedEntities = true
+	}
+}
+
+// WithAllowedActions restricts the events with the given name to the given actions,
+// e.g. WithAllowedActions("pull_request", "opened", "synchronize", "reopened").
+// Events with any other action are skipped without resolving their targets.
+func WithAllowedActions(eventName string, actions ...string) Option {
+	return func(o *options) {
+		o.allowedActions[eventName] = append(o.allowedActions[eventName], actions...)
+	}
+}
+
+// WithDeniedActions skips the events with the given name and one of the given actions,
+// e.g. WithDeniedActions("pull_request", "labeled", "edited").
+func WithDeniedActions(eventName string, actions ...string) Option {
+	return func(o *options) {
+		o.deniedActions[eventName] = append(o.deniedActions[eventName], actions...)
+	}
+}
+
+// WithBotLogins skips the events triggered by any of the given logins,
+// e.g. the account used by reviewpad to label and comment, or the login of
+// its GitHub App (e.g. "reviewpad[bot]") as returned by AppTokenSource.Login.
+func WithBotLogins(logins ...string) Option {
+	return func(o *options) {
+		o.botLogins = append(o.botLogins, logins...)
+	}
+}
+
+// WithoutBotSenderDetection processes the events triggered by GitHub Apps, whose logins
+// end with "[bot]", such as dependabot, renovate or github-actions, which are skipped by default.
+// The events triggered by the logins given with WithBotLogins are still skipped.
+func WithoutBotSenderDetection() Option {
+	return func(o *options) {
+		o.botSenderDetection = false
+	}
+}
+
+// WithoutTokenIdentityDetection processes the events triggered by the user that owns the token,
+// which are skipped by default.
+// The user is fetched from the GitHub API for each event with a sender, except for the
+// installation tokens of GitHub Apps, whose login must be given with WithBotLogins.
+func WithoutTokenIdentityDetection() Option {
+	return func(o *options) {
+		o.tokenIdentityDetection = false
+	}
+}
+
+// WithClosedEntities keeps the closed pull requests, issues and discussions
+// among the targets, which are skipped by default.
+func WithClosedEntities() Option {
+	return func(o *options) {
+		o.includeClosed = true
+	}
+}
+
+// WithMergedEntities keeps the merged pull requests among the targets,
+// which are skipped by default.
+func WithMergedEntities() Option {
+	return func(o *options) {
+		o.includeMerged = true
+	}
+}
+
+// WithLockedEntities keeps the locked pull requests, issues and discussions
+// among the targets, which are skipped by default.
+func WithLockedEntities() Option {
+	return func(o *options) {
+		o.includeLocked = true
+	}
+}
+
+// WithCache stores the responses of the GitHub API in the given cache and revalidates them
+// with conditional requests, which do not count against the rate limit when the response
+// was not modified, e.g. WithCache(NewMemoryCache(0)) in long-running processes.
+// The responses are cached by the installation of the GitHub App that the event was
+// delivered to, or by the identity set with WithCacheIdentity, and otherwise by token.
+func WithCache(cache Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithCacheIdentity caches the responses by the given identity of the token instead of
+// by the token itself, e.g. "installation 42" for the tokens of a GitHub App installation,
+// which expire every hour.
+func WithCacheIdentity(identity string) Option {
+	return func(o *options) {
+		o.cacheIdentity = identity
+	}
+}
+
+// WithShaResolver looks up the pull request of the head SHA of 'status', 'workflow_run',
+// 'deployment' and 'deployment_status' events with the given resolver, which batches the
+// lookups of the events processed at the same time into a single GraphQL query.
+// The lookup falls back to listing the open pull requests when the query fails.
+func WithShaResolver(resolver *ShaResolver) Option {
+	return func(o *options) {
+		o.shaResolver = resolver
+	}
+}
+
+// WithLogger writes the logs of the handler with the given logger instead of DefaultLogger.
+// The entries about an event have the event_name, delivery_id, owner and repo fields.
+func WithLogger(logger *Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithMetrics records the events processed by ProcessEvent, their targets and errors,
+// and the requests to the GitHub API in the given metrics.
+func WithMetrics(metrics *Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+// WithTracerProvider records the spans of ProcessEvent, covering the parsing of the event,
+// its processor and each request to the GitHub API, with the given provider instead of
+// the global provider of OpenTelemetry, which does not record them unless it is set.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// WithContext sets the context in which the event is processed, e.g. to continue the trace
+// of the request that delivered the event.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+func allowedBy(allowed []string, value string) bool {
+	return len(allowed) == 0 || contains(allowed, value)
+}
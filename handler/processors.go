@@ -7,27 +7,66 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v45/github"
 	reviewpad_gh "github.com/reviewpad/reviewpad/v3/codehost/github"
+	"github.com/shurcooL/githubv4"
 )
 
 const (
 	PullRequest TargetEntityKind = "pull_request"
 	Issue       TargetEntityKind = "issue"
+	Discussion  TargetEntityKind = "discussion"
+)
+
+var (
+	ErrUnknownTargetEntityKind = errors.New("unknown target entity kind")
+	// ErrMissingToken is returned for the events whose targets are resolved with requests
+	// to the GitHub API when the event has no token.
+	ErrMissingToken = errors.New("missing token")
 )
 
 type TargetEntityKind string
 
 func (entityType TargetEntityKind) String() string {
 	switch entityType {
+	case PullRequest:
+		return "pull"
 	case Issue:
 		return "issue"
+	case Discussion:
+		return "discussion"
+	}
+	return fmt.Sprintf("unknown(%s)", string(entityType))
+}
+
+func (entityType TargetEntityKind) Validate() error {
+	switch entityType {
+	case PullRequest, Issue, Discussion:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownTargetEntityKind, string(entityType))
+}
+
+func (entityType TargetEntityKind) MarshalText() ([]byte, error) {
+	if err := entityType.Validate(); err != nil {
+		return nil, err
 	}
-	return "pull"
+	return []byte(entityType), nil
+}
+
+func (entityType *TargetEntityKind) UnmarshalText(text []byte) error {
+	kind := TargetEntityKind(text)
+	if err := kind.Validate(); err != nil {
+		return err
+	}
+	*entityType = kind
+	return nil
 }
 
 type TargetEntity struct {
@@ -35,12 +74,18 @@ type TargetEntity struct {
 	Number int
 	Owner  string
 	Repo   string
+	// Derived is set when the entity is not the subject of the event
+	// but was reached by expanding it (e.g. pull requests stacked on top of it).
+	Derived bool
+	// Reason describes why the entity was selected as a target of the event
+	// (e.g. "head SHA of status event").
+	Reason string
 }
 
 func ParseEvent(rawEvent string) (*ActionEvent, error) {
 	event := &ActionEvent{}
 
-	Log("parsing event %v", rawEvent)
+	DefaultLogger().Debug("parsing event")
 
 	err := json.Unmarshal([]byte(rawEvent), &event)
 	if err != nil {
@@ -50,13 +95,13 @@ func ParseEvent(rawEvent string) (*ActionEvent, error) {
 	return event, nil
 }
 
-func processCronEvent(token string, e *ActionEvent) ([]*TargetEntity, error) {
-	Log("processing 'schedule' event")
+func processCronEvent(token string, e *ActionEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'schedule' event")
 
-	ctx, canc := context.WithTimeout(context.Background(), time.Minute*10)
+	ctx, canc := context.WithTimeout(opts.ctx, time.Minute*10)
 	defer canc()
 
-	ghClient := reviewpad_gh.NewGithubClientFromToken(ctx, token)
+	ghClient := newGithubClient(ctx, token, opts)
 
 	repoParts := strings.SplitN(*e.Repository, "/", 2)
 	prs, err := ghClient.GetPullRequests(ctx, repoParts[0], repoParts[1])
@@ -64,26 +109,25 @@ func processCronEvent(token string, e *ActionEvent) ([]*TargetEntity, error) {
 		return nil, fmt.Errorf("get pull requests: %w", err)
 	}
 
-	Log("fetched %d prs", len(prs))
+	opts.logger.Debug("fetched pull requests", Fields{"count": len(prs)})
 
 	events := make([]*TargetEntity, 0)
 	for _, pr := range prs {
-		events = append(events, &TargetEntity{
-			Kind:   PullRequest,
-			Number: *pr.Number,
-			Owner:  *pr.Base.Repo.Owner.Login,
-			Repo:   *pr.Base.Repo.Name,
-		})
+		target := pullRequestTarget(pr)
+		target.Reason = "cron sweep"
+
+		opts.setPullRequestState(target, pr)
+		events = append(events, target)
 	}
 
-	Log("found events %v", events)
+	opts.logger.Debug("found targets", Fields{"count": len(events)})
 
 	return events, nil
 }
 
-func processIssuesEvent(e *github.IssuesEvent) []*TargetEntity {
-	Log("processing 'issues' event")
-	Log("found issue %v", *e.Issue.Number)
+func processIssuesEvent(e *github.IssuesEvent, opts *options) []*TargetEntity {
+	opts.logger.Debug("processing 'issues' event")
+	opts.logger.Info("found issue", Fields{"number": *e.Issue.Number})
 
 	return []*TargetEntity{
 		{
@@ -91,13 +135,14 @@ func processIssuesEvent(e *github.IssuesEvent) []*TargetEntity {
 			Number: *e.Issue.Number,
 			Owner:  *e.Repo.Owner.Login,
 			Repo:   *e.Repo.Name,
+			Reason: "subject of issues event",
 		},
 	}
 }
 
-func processIssueCommentEvent(e *github.IssueCommentEvent) []*TargetEntity {
-	Log("processing 'issue_comment' event")
-	Log("found issue %v", *e.Issue.Number)
+func processIssueCommentEvent(e *github.IssueCommentEvent, opts *options) []*TargetEntity {
+	opts.logger.Debug("processing 'issue_comment' event")
+	opts.logger.Info("found issue", Fields{"number": *e.Issue.Number})
 
 	return []*TargetEntity{
 		{
@@ -105,27 +150,36 @@ func processIssueCommentEvent(e *github.IssueCommentEvent) []*TargetEntity {
 			Number: *e.Issue.Number,
 			Owner:  *e.Repo.Owner.Login,
 			Repo:   *e.Repo.Name,
+			Reason: "subject of issue_comment event",
 		},
 	}
 }
 
-func processPullRequestEvent(e *github.PullRequestEvent) []*TargetEntity {
-	Log("processing 'pull_request' event")
-	Log("found pr %v", *e.PullRequest.Number)
+func processPullRequestEvent(token *string, e *github.PullRequestEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'pull_request' event")
+	opts.logger.Info("found pull request", Fields{"number": *e.PullRequest.Number})
 
-	return []*TargetEntity{
+	targets := []*TargetEntity{
 		{
 			Kind:   PullRequest,
 			Number: *e.PullRequest.Number,
 			Owner:  *e.Repo.Owner.Login,
 			Repo:   *e.Repo.Name,
+			Reason: "subject of pull_request event",
 		},
 	}
+
+	stackedTargets, err := getStackedPullRequests(token, e.GetAction(), e.PullRequest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(targets, stackedTargets...), nil
 }
 
-func processPullRequestReviewEvent(e *github.PullRequestReviewEvent) []*TargetEntity {
-	Log("processing 'pull_request_review' event")
-	Log("found pr %v", *e.PullRequest.Number)
+func processPullRequestReviewEvent(e *github.PullRequestReviewEvent, opts *options) []*TargetEntity {
+	opts.logger.Debug("processing 'pull_request_review' event")
+	opts.logger.Info("found pull request", Fields{"number": *e.PullRequest.Number})
 
 	return []*TargetEntity{
 		{
@@ -133,13 +187,14 @@ func processPullRequestReviewEvent(e *github.PullRequestReviewEvent) []*TargetEn
 			Number: *e.PullRequest.Number,
 			Owner:  *e.Repo.Owner.Login,
 			Repo:   *e.Repo.Name,
+			Reason: "subject of pull_request_review event",
 		},
 	}
 }
 
-func processPullRequestReviewCommentEvent(e *github.PullRequestReviewCommentEvent) []*TargetEntity {
-	Log("processing 'pull_request_review_comment' event")
-	Log("found pr %v", *e.PullRequest.Number)
+func processPullRequestReviewCommentEvent(e *github.PullRequestReviewCommentEvent, opts *options) []*TargetEntity {
+	opts.logger.Debug("processing 'pull_request_review_comment' event")
+	opts.logger.Info("found pull request", Fields{"number": *e.PullRequest.Number})
 
 	return []*TargetEntity{
 		{
@@ -147,101 +202,664 @@ func processPullRequestReviewCommentEvent(e *github.PullRequestReviewCommentEven
 			Number: *e.PullRequest.Number,
 			Owner:  *e.Repo.Owner.Login,
 			Repo:   *e.Repo.Name,
+			Reason: "subject of pull_request_review_comment event",
 		},
 	}
 }
 
-func processPullRequestTargetEvent(e *github.PullRequestTargetEvent) []*TargetEntity {
-	Log("processing 'pull_request_target' event")
-	Log("found pr %v", *e.PullRequest.Number)
+func processPullRequestTargetEvent(token *string, e *github.PullRequestTargetEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'pull_request_target' event")
+	opts.logger.Info("found pull request", Fields{"number": *e.PullRequest.Number})
 
-	return []*TargetEntity{
+	targets := []*TargetEntity{
 		{
 			Kind:   PullRequest,
 			Number: *e.PullRequest.Number,
 			Owner:  *e.Repo.Owner.Login,
 			Repo:   *e.Repo.Name,
+			Reason: "subject of pull_request_target event",
 		},
 	}
+
+	stackedTargets, err := getStackedPullRequests(token, e.GetAction(), e.PullRequest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(targets, stackedTargets...), nil
 }
 
-func processStatusEvent(token string, e *github.StatusEvent) ([]*TargetEntity, error) {
-	Log("processing 'status' event")
+// getStackedPullRequests returns the open pull requests stacked on top of the given pull request,
+// i.e. whose base branch is the head branch of the pull request, when the pull request
+// was merged or its head branch was updated.
+// The stacked pull requests are not looked up when the event has no token.
+func getStackedPullRequests(token *string, action string, pr *github.PullRequest, opts *options) ([]*TargetEntity, error) {
+	merged := action == "closed" && pr.GetMerged()
+	headUpdated := action == "synchronize"
+	if !merged && !headUpdated {
+		return []*TargetEntity{}, nil
+	}
+
+	if token == nil {
+		opts.logger.Warn("skipping stacked pull requests", Fields{"number": pr.GetNumber(), "reason": "missing token"})
+		return []*TargetEntity{}, nil
+	}
+
+	// Pull requests from forks cannot be stacked on top of, since their
+	// head branch does not exist in the base repository.
+	if pr.Head.GetRepo().GetFullName() != pr.Base.GetRepo().GetFullName() {
+		return []*TargetEntity{}, nil
+	}
+
+	owner := *pr.Base.Repo.Owner.Login
+	repo := *pr.Base.Repo.Name
+	headRef := *pr.Head.Ref
+
+	opts.logger.Debug("looking for pull requests stacked on the branch", Fields{"branch": headRef})
 
-	ctx, canc := context.WithTimeout(context.Background(), time.Minute*10)
+	criteria := fmt.Sprintf("base branch %v", headRef)
+	prs, err := getPullRequestsMatching(*token, owner, repo, criteria, opts, func(stackedPR *github.PullRequest) bool {
+		return stackedPR.Base.GetRef() == headRef && stackedPR.GetNumber() != pr.GetNumber()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*TargetEntity, 0)
+	for _, stackedPR := range prs {
+		target := pullRequestTarget(stackedPR)
+		target.Derived = true
+		target.Reason = fmt.Sprintf("stacked on #%v", *pr.Number)
+
+		opts.setPullRequestState(target, stackedPR)
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+func processDiscussionEvent(e *github.DiscussionEvent, opts *options) []*TargetEntity {
+	opts.logger.Debug("processing 'discussion' event")
+	opts.logger.Info("found discussion", Fields{"number": *e.Discussion.Number})
+
+	return []*TargetEntity{
+		{
+			Kind:   Discussion,
+			Number: *e.Discussion.Number,
+			Owner:  *e.Repo.Owner.Login,
+			Repo:   *e.Repo.Name,
+			Reason: "subject of discussion event",
+		},
+	}
+}
+
+func processDiscussionCommentEvent(e *DiscussionCommentEvent, opts *options) []*TargetEntity {
+	opts.logger.Debug("processing 'discussion_comment' event")
+	opts.logger.Info("found discussion", Fields{"number": *e.Discussion.Number})
+
+	return []*TargetEntity{
+		{
+			Kind:   Discussion,
+			Number: *e.Discussion.Number,
+			Owner:  *e.Repo.Owner.Login,
+			Repo:   *e.Repo.Name,
+			Reason: "subject of discussion_comment event",
+		},
+	}
+}
+
+// getPullRequestByHead looks for the open pull request whose head is at the given sha.
+// When no pull request matches the sha and a ref is provided, the pull request whose
+// head branch in the repository is the ref is used instead, since the ref of a
+// deployment is a branch of the repository and not of a fork.
+func getPullRequestByHead(token, owner, repo, sha, ref, eventName string, opts *options) ([]*TargetEntity, error) {
+	if opts.shaResolver != nil {
+		targets, err := resolvePullRequestByHead(token, owner, repo, sha, eventName, opts)
+		if err != nil {
+			opts.logger.Warn("failed to resolve sha, listing pull requests instead", Fields{"sha": sha, "error": err})
+		} else if len(targets) > 0 || ref == "" {
+			return targets, nil
+		}
+	}
+
+	ctx, canc := context.WithTimeout(opts.ctx, time.Minute*10)
 	defer canc()
 
-	ghClient := reviewpad_gh.NewGithubClientFromToken(ctx, token)
+	ghClient := newGithubClient(ctx, token, opts)
 
-	prs, err := ghClient.GetPullRequests(ctx, *e.Repo.Owner.Login, *e.Repo.Name)
+	prs, err := ghClient.GetPullRequests(ctx, owner, repo)
 	if err != nil {
 		return nil, fmt.Errorf("get pull requests: %w", err)
 	}
 
-	Log("fetched %v prs", len(prs))
+	opts.logger.Debug("fetched pull requests", Fields{"count": len(prs)})
+
+	reason := fmt.Sprintf("head SHA of %v event", eventName)
+	pr := findPullRequest(prs, func(pr *github.PullRequest) bool {
+		return pr.Head.GetSHA() == sha
+	})
+
+	if pr == nil {
+		opts.logger.Info("no pull request found with the head sha", Fields{"sha": sha})
+
+		if ref != "" {
+			reason = fmt.Sprintf("head ref of %v event", eventName)
+			pr = findPullRequest(prs, func(pr *github.PullRequest) bool {
+				return pr.Head.GetRef() == ref && hasHeadInBaseRepository(pr)
+			})
+
+			if pr == nil {
+				opts.logger.Info("no pull request found with the head ref", Fields{"ref": ref})
+			}
+		}
+	}
+
+	for _, otherPR := range prs {
+		if otherPR == pr {
+			continue
+		}
+
+		switch {
+		case otherPR.Head.GetSHA() == sha || (ref != "" && otherPR.Head.GetRef() == ref && hasHeadInBaseRepository(otherPR)):
+			opts.drop(pullRequestTarget(otherPR), fmt.Sprintf("head also matches but #%v was selected", *pr.Number))
+		case ref != "" && otherPR.Head.GetRef() == ref:
+			opts.drop(pullRequestTarget(otherPR), fmt.Sprintf("head branch %v is in the fork %v", ref, otherPR.Head.GetRepo().GetFullName()))
+		case ref != "":
+			opts.drop(pullRequestTarget(otherPR), fmt.Sprintf("head %v (%v) does not match SHA %v or ref %v", otherPR.Head.GetSHA(), otherPR.Head.GetRef(), sha, ref))
+		default:
+			opts.drop(pullRequestTarget(otherPR), fmt.Sprintf("head %v does not match SHA %v", otherPR.Head.GetSHA(), sha))
+		}
+	}
+
+	if pr == nil {
+		return []*TargetEntity{}, nil
+	}
+
+	opts.logger.Info("found pull request", Fields{"number": *pr.Number})
+
+	target := pullRequestTarget(pr)
+	target.Reason = reason
+
+	opts.setPullRequestState(target, pr)
+
+	return []*TargetEntity{target}, nil
+}
 
+func pullRequestTarget(pr *github.PullRequest) *TargetEntity {
+	return &TargetEntity{
+		Kind:   PullRequest,
+		Number: *pr.Number,
+		Owner:  *pr.Base.Repo.Owner.Login,
+		Repo:   *pr.Base.Repo.Name,
+	}
+}
+
+func findPullRequest(prs []*github.PullRequest, match func(*github.PullRequest) bool) *github.PullRequest {
 	for _, pr := range prs {
-		if *pr.Head.SHA == *e.SHA {
-			Log("found pr %v", *pr.Number)
-			return []*TargetEntity{
-				{
-					Kind:   PullRequest,
-					Number: *pr.Number,
-					Owner:  *pr.Base.Repo.Owner.Login,
-					Repo:   *pr.Base.Repo.Name,
-				},
-			}, nil
+		if match(pr) {
+			return pr
 		}
 	}
+	return nil
+}
 
-	Log("no pr found with the head sha %v", *e.SHA)
+func processStatusEvent(token string, e *github.StatusEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'status' event")
 
-	return []*TargetEntity{}, nil
+	return getPullRequestByHead(token, *e.Repo.Owner.Login, *e.Repo.Name, *e.SHA, "", "status", opts)
 }
 
-func processWorkflowRunEvent(token string, e *github.WorkflowRunEvent) ([]*TargetEntity, error) {
-	Log("processing 'workflow_run' event")
+func processWorkflowRunEvent(token string, e *github.WorkflowRunEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'workflow_run' event")
 
-	ctx, canc := context.WithTimeout(context.Background(), time.Minute*10)
+	return getPullRequestByHead(token, *e.Repo.Owner.Login, *e.Repo.Name, *e.WorkflowRun.HeadSHA, "", "workflow_run", opts)
+}
+
+func processDeploymentEvent(token string, e *github.DeploymentEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'deployment' event")
+
+	environment := e.Deployment.GetEnvironment()
+	if !allowedBy(opts.deploymentEnvironments, environment) {
+		opts.skip(fmt.Sprintf("deployment environment %v is not allowed", environment))
+		return []*TargetEntity{}, nil
+	}
+
+	return getPullRequestByHead(token, *e.Repo.Owner.Login, *e.Repo.Name, e.Deployment.GetSHA(), e.Deployment.GetRef(), "deployment", opts)
+}
+
+func processDeploymentStatusEvent(token string, e *github.DeploymentStatusEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'deployment_status' event")
+
+	environment := e.DeploymentStatus.GetEnvironment()
+	if environment == "" {
+		environment = e.Deployment.GetEnvironment()
+	}
+
+	if !allowedBy(opts.deploymentEnvironments, environment) {
+		opts.skip(fmt.Sprintf("deployment environment %v is not allowed", environment))
+		return []*TargetEntity{}, nil
+	}
+
+	state := e.DeploymentStatus.GetState()
+	if !allowedBy(opts.deploymentStates, state) {
+		opts.skip(fmt.Sprintf("deployment state %v is not allowed", state))
+		return []*TargetEntity{}, nil
+	}
+
+	return getPullRequestByHead(token, *e.Repo.Owner.Login, *e.Repo.Name, e.Deployment.GetSHA(), e.Deployment.GetRef(), "deployment_status", opts)
+}
+
+func getPullRequestsMatching(token, owner, repo, criteria string, opts *options, match func(*github.PullRequest) bool) ([]*github.PullRequest, error) {
+	ctx, canc := context.WithTimeout(opts.ctx, time.Minute*10)
 	defer canc()
-	ghClient := reviewpad_gh.NewGithubClientFromToken(ctx, token)
 
-	prs, err := ghClient.GetPullRequests(ctx, *e.Repo.Owner.Login, *e.Repo.Name)
+	ghClient := newGithubClient(ctx, token, opts)
+
+	prs, err := ghClient.GetPullRequests(ctx, owner, repo)
 	if err != nil {
 		return nil, fmt.Errorf("get pull requests: %w", err)
 	}
 
-	Log("fetched %v prs", len(prs))
+	opts.logger.Debug("fetched pull requests", Fields{"count": len(prs)})
 
+	matches := make([]*github.PullRequest, 0)
 	for _, pr := range prs {
-		if *pr.Head.SHA == *e.WorkflowRun.HeadSHA {
-			Log("found pr %v", *pr.Number)
-			return []*TargetEntity{
-				{
-					Kind:   PullRequest,
-					Number: *pr.Number,
-					Owner:  *pr.Base.Repo.Owner.Login,
-					Repo:   *pr.Base.Repo.Name,
-				},
-			}, nil
+		if !match(pr) {
+			opts.drop(pullRequestTarget(pr), fmt.Sprintf("does not match %v", criteria))
+			continue
+		}
+
+		opts.logger.Info("found pull request", Fields{"number": *pr.Number})
+		matches = append(matches, pr)
+	}
+
+	return matches, nil
+}
+
+// hasHeadInBaseRepository reports whether the head branch of the pull request is in its
+// base repository, i.e. the pull request is not from a fork.
+func hasHeadInBaseRepository(pr *github.PullRequest) bool {
+	return pr.Head.GetRepo().GetFullName() == pr.Base.GetRepo().GetFullName()
+}
+
+// getPullRequestsByBranch looks for the pull requests whose base branch, or head branch in
+// the repository, is the given branch. The head branches of the forks are other branches.
+func getPullRequestsByBranch(token, owner, repo, branch, eventName string, opts *options) ([]*TargetEntity, error) {
+	criteria := fmt.Sprintf("base or head branch %v", branch)
+	prs, err := getPullRequestsMatching(token, owner, repo, criteria, opts, func(pr *github.PullRequest) bool {
+		return pr.Base.GetRef() == branch || (pr.Head.GetRef() == branch && hasHeadInBaseRepository(pr))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(prs) == 0 {
+		opts.logger.Info("no pull request found with the base or head branch", Fields{"branch": branch})
+	}
+
+	targets := make([]*TargetEntity, 0)
+	for _, pr := range prs {
+		target := pullRequestTarget(pr)
+		target.Reason = fmt.Sprintf("%v of %v event", criteria, eventName)
+
+		opts.setPullRequestState(target, pr)
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+func processCreateEvent(token string, e *github.CreateEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'create' event")
+
+	if e.GetRefType() != "branch" {
+		opts.skip(fmt.Sprintf("created %v %v is not a branch", e.GetRefType(), e.GetRef()))
+		return []*TargetEntity{}, nil
+	}
+
+	return getPullRequestsByBranch(token, *e.Repo.Owner.Login, *e.Repo.Name, *e.Ref, "create", opts)
+}
+
+func processDeleteEvent(token string, e *github.DeleteEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'delete' event")
+
+	if e.GetRefType() != "branch" {
+		opts.skip(fmt.Sprintf("deleted %v %v is not a branch", e.GetRefType(), e.GetRef()))
+		return []*TargetEntity{}, nil
+	}
+
+	return getPullRequestsByBranch(token, *e.Repo.Owner.Login, *e.Repo.Name, *e.Ref, "delete", opts)
+}
+
+func getPreviousRelease(ctx context.Context, ghClient *reviewpad_gh.GithubClient, owner, repo string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	releases, _, err := ghClient.GetClientREST().Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{
+		PerPage: 100,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Releases are listed from the newest to the oldest.
+	for _, r := range releases {
+		if r.GetDraft() || r.GetTagName() == release.GetTagName() {
+			continue
 		}
+
+		if r.GetCreatedAt().Before(release.GetCreatedAt().Time) {
+			return r, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func processReleaseEvent(token string, e *github.ReleaseEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'release' event")
+
+	ctx, canc := context.WithTimeout(opts.ctx, time.Minute*10)
+	defer canc()
+
+	ghClient := newGithubClient(ctx, token, opts)
+
+	owner := *e.Repo.Owner.Login
+	repo := *e.Repo.Name
+	tag := *e.Release.TagName
+
+	previousRelease, err := getPreviousRelease(ctx, ghClient, owner, repo, e.Release)
+	if err != nil {
+		return nil, fmt.Errorf("get previous release: %w", err)
+	}
+
+	if previousRelease == nil {
+		opts.skip(fmt.Sprintf("no release found before %v", tag))
+		return []*TargetEntity{}, nil
+	}
+
+	previousTag := *previousRelease.TagName
+
+	opts.logger.Debug("comparing release with the previous release", Fields{"tag": tag, "previous_tag": previousTag})
+
+	commits := make([]*github.RepositoryCommit, 0)
+	listOpts := &github.ListOptions{
+		PerPage: 100,
+	}
+	for len(commits) < opts.releaseMaxCommits {
+		comparison, resp, err := ghClient.GetClientREST().Repositories.CompareCommits(ctx, owner, repo, previousTag, tag, listOpts)
+		if err != nil {
+			return nil, fmt.Errorf("compare commits: %w", err)
+		}
+
+		commits = append(commits, comparison.Commits...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+
+	if len(commits) > opts.releaseMaxCommits {
+		opts.logger.Warn("limiting the commits of the release", Fields{"count": len(commits), "limit": opts.releaseMaxCommits})
+		commits = commits[:opts.releaseMaxCommits]
+	}
+
+	opts.logger.Debug("fetched commits", Fields{"count": len(commits)})
+
+	targets := make([]*TargetEntity, 0)
+	found := make(map[int]bool)
+	for _, commit := range commits {
+		prs, _, err := ghClient.GetClientREST().PullRequests.ListPullRequestsWithCommit(ctx, owner, repo, *commit.SHA, nil)
+		if err != nil {
+			return nil, fmt.Errorf("list pull requests with commit: %w", err)
+		}
+
+		for _, pr := range prs {
+			if found[*pr.Number] {
+				continue
+			}
+
+			target := &TargetEntity{
+				Kind:   PullRequest,
+				Number: *pr.Number,
+				Owner:  owner,
+				Repo:   repo,
+				Reason: fmt.Sprintf("merged in release %v", tag),
+			}
+
+			if pr.MergedAt == nil {
+				opts.drop(target, fmt.Sprintf("contains commit %v but is not merged", *commit.SHA))
+				continue
+			}
+
+			opts.logger.Info("found pull request", Fields{"number": *pr.Number})
+
+			found[*pr.Number] = true
+			opts.setPullRequestState(target, pr)
+			targets = append(targets, target)
+		}
+	}
+
+	opts.logger.Debug("found targets", Fields{"count": len(targets)})
+
+	return targets, nil
+}
+
+type projectsV2ItemContentQuery struct {
+	Node struct {
+		Issue       linkedEntity `graphql:"... on Issue"`
+		PullRequest linkedEntity `graphql:"... on PullRequest"`
+	} `graphql:"node(id: $id)"`
+}
+
+func processProjectsV2ItemEvent(token string, e *ProjectsV2ItemEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'projects_v2_item' event")
+
+	var kind TargetEntityKind
+	switch contentType := e.ProjectsV2Item.GetContentType(); contentType {
+	case "Issue":
+		kind = Issue
+	case "PullRequest":
+		kind = PullRequest
+	default:
+		opts.skip(fmt.Sprintf("project item content type %v is not an issue or a pull request", contentType))
+		return []*TargetEntity{}, nil
+	}
+
+	ctx, canc := context.WithTimeout(opts.ctx, time.Minute*10)
+	defer canc()
+
+	ghClient := newGithubClient(ctx, token, opts)
+
+	var query projectsV2ItemContentQuery
+	err := ghClient.GetClientGraphQL().Query(ctx, &query, map[string]interface{}{
+		"id": githubv4.ID(e.ProjectsV2Item.GetContentNodeID()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get project item content: %w", err)
+	}
+
+	content := query.Node.Issue
+	if kind == PullRequest {
+		content = query.Node.PullRequest
+	}
+
+	// The node is null when the content was deleted or is not accessible with the token.
+	if content.Number == 0 {
+		opts.skip(fmt.Sprintf("project item content %v was not found", e.ProjectsV2Item.GetContentNodeID()))
+		return []*TargetEntity{}, nil
+	}
+
+	opts.logger.Info("found project item content", Fields{"kind": kind, "owner": content.Repository.Owner.Login, "repo": content.Repository.Name, "number": content.Number})
+
+	target := &TargetEntity{
+		Kind:   kind,
+		Number: content.Number,
+		Owner:  content.Repository.Owner.Login,
+		Repo:   content.Repository.Name,
+		Reason: "content of projects_v2_item event",
+	}
+
+	opts.setState(target, content.State, content.Locked, false)
+
+	return []*TargetEntity{target}, nil
+}
+
+func processMilestoneEvent(token string, e *github.MilestoneEvent, opts *options) ([]*TargetEntity, error) {
+	opts.logger.Debug("processing 'milestone' event")
+
+	ctx, canc := context.WithTimeout(opts.ctx, time.Minute*10)
+	defer canc()
+
+	ghClient := newGithubClient(ctx, token, opts)
+
+	owner := *e.Repo.Owner.Login
+	repo := *e.Repo.Name
+
+	listOpts := &github.IssueListByRepoOptions{
+		Milestone: strconv.Itoa(*e.Milestone.Number),
+		State:     "open",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	issues := make([]*github.Issue, 0)
+	for {
+		pageIssues, resp, err := ghClient.ListIssuesByRepo(ctx, owner, repo, listOpts)
+		if err != nil {
+			return nil, fmt.Errorf("list issues by repo: %w", err)
+		}
+
+		issues = append(issues, pageIssues...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+
+	opts.logger.Debug("fetched issues and pull requests of the milestone", Fields{"count": len(issues), "milestone": *e.Milestone.Number})
+
+	targets := make([]*TargetEntity, 0)
+	for _, issue := range issues {
+		kind := Issue
+		if issue.IsPullRequest() {
+			kind = PullRequest
+		}
+
+		target := &TargetEntity{
+			Kind:   kind,
+			Number: *issue.Number,
+			Owner:  owner,
+			Repo:   repo,
+			Reason: fmt.Sprintf("in milestone #%v", *e.Milestone.Number),
+		}
+
+		opts.setState(target, issue.GetState(), issue.GetLocked(), false)
+		targets = append(targets, target)
 	}
 
-	Log("no pr found with the head sha %v", *e.WorkflowRun.HeadSHA)
+	opts.logger.Debug("found targets", Fields{"count": len(targets)})
 
-	return []*TargetEntity{}, nil
+	return targets, nil
 }
 
 // reviewpad-an: critical
 // output: the list of pull requests/issues that are affected by the event.
-func ProcessEvent(event *ActionEvent) ([]*TargetEntity, error) {
+func ProcessEvent(event *ActionEvent, opts ...Option) ([]*TargetEntity, error) {
+	options := newOptions(opts)
+	options.logger = options.logger.WithFields(eventFields(event))
+
+	// The tokens of the installation that the event was delivered to share its cached responses.
+	if installationID := getEventInstallationID(event); installationID != 0 {
+		options.cacheIdentity = fmt.Sprintf("installation %v", installationID)
+	}
+
+	options, span := options.startSpan("ProcessEvent", eventAttributes(event)...)
+	defer span.End()
+
+	start := time.Now()
+	targets, err := processEventTargets(event, options)
+	recordResult(span, targets, err)
+
+	if options.metrics != nil {
+		options.metrics.observeEvent(*event.EventName, getEventAction(event), time.Since(start).Seconds(), targets, err)
+	}
+
+	return targets, err
+}
+
+func processEventTargets(event *ActionEvent, opts *options) ([]*TargetEntity, error) {
+	if event.QraphqlUrl != nil && *event.QraphqlUrl != "" {
+		opts.graphqlURL = *event.QraphqlUrl
+	}
+
+	if reason := filterAction(event, opts); reason != "" {
+		opts.skip(reason)
+		return []*TargetEntity{}, nil
+	}
+
+	if reason := filterSender(event, opts); reason != "" {
+		opts.skip(reason)
+		return []*TargetEntity{}, nil
+	}
+
+	targets, err := processEvent(event, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.linkedEntities {
+		targets, err = traceStep("expandLinkedEntities", opts, func(opts *options) ([]*TargetEntity, error) {
+			return expandLinkedEntities(event, targets, opts)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The targets of 'release' events are the pull requests merged into the release.
+	if *event.EventName == "release" {
+		return targets, nil
+	}
+
+	return traceStep("filterStates", opts, func(opts *options) ([]*TargetEntity, error) {
+		return filterStates(event, targets, opts)
+	})
+}
+
+// parseEventPayload parses the payload of the event into the type of its processor.
+func parseEventPayload(event *ActionEvent, opts *options) (interface{}, error) {
+	_, span := opts.startSpan("parseEventPayload")
+	defer span.End()
+
+	payload, err := parseEventPayloadType(event)
+	if err != nil {
+		recordError(span, err)
+	}
+
+	return payload, err
+}
+
+func parseEventPayloadType(event *ActionEvent) (interface{}, error) {
 	// These events do not have an equivalent in the GitHub webhooks, thus
 	// parsing them with github.ParseWebhook would return an error.
 	// These are the webhook events: https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads
 	// And these are the "workflow events": https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows
 	switch *event.EventName {
 	case "schedule":
-		return processCronEvent(*event.Token, event)
+		return event, nil
+	case "discussion_comment":
+		payload := &DiscussionCommentEvent{}
+		if err := json.Unmarshal(*event.EventPayload, payload); err != nil {
+			return nil, fmt.Errorf("parse discussion comment event: %w", err)
+		}
+		return payload, nil
+	case "projects_v2_item":
+		payload := &ProjectsV2ItemEvent{}
+		if err := json.Unmarshal(*event.EventPayload, payload); err != nil {
+			return nil, fmt.Errorf("parse projects v2 item event: %w", err)
+		}
+		return payload, nil
 	}
 
 	eventPayload, err := github.ParseWebHook(*event.EventName, *event.EventPayload)
@@ -249,25 +867,119 @@ func ProcessEvent(event *ActionEvent) ([]*TargetEntity, error) {
 		return nil, fmt.Errorf("parse github webhook: %w", err)
 	}
 
+	return eventPayload, nil
+}
+
+// withToken processes the event with its token, or returns ErrMissingToken when it has none.
+func withToken(event *ActionEvent, process func(token string) ([]*TargetEntity, error)) ([]*TargetEntity, error) {
+	if event.Token == nil {
+		return nil, fmt.Errorf("%w for %v events", ErrMissingToken, *event.EventName)
+	}
+
+	return process(*event.Token)
+}
+
+func processEvent(event *ActionEvent, opts *options) ([]*TargetEntity, error) {
+	eventPayload, err := parseEventPayload(event, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	switch payload := eventPayload.(type) {
+	case *ActionEvent:
+		return traceStep("processCronEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return withToken(event, func(token string) ([]*TargetEntity, error) {
+				return processCronEvent(token, payload, opts)
+			})
+		})
+	case *DiscussionCommentEvent:
+		return traceStep("processDiscussionCommentEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return processDiscussionCommentEvent(payload, opts), nil
+		})
+	case *ProjectsV2ItemEvent:
+		return traceStep("processProjectsV2ItemEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return withToken(event, func(token string) ([]*TargetEntity, error) {
+				return processProjectsV2ItemEvent(token, payload, opts)
+			})
+		})
 	// Handle github events triggered by actions
 	// For more information, visit: https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows
 	case *github.IssuesEvent:
-		return processIssuesEvent(payload), nil
+		return traceStep("processIssuesEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return processIssuesEvent(payload, opts), nil
+		})
 	case *github.IssueCommentEvent:
-		return processIssueCommentEvent(payload), nil
+		return traceStep("processIssueCommentEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return processIssueCommentEvent(payload, opts), nil
+		})
 	case *github.PullRequestEvent:
-		return processPullRequestEvent(payload), nil
+		return traceStep("processPullRequestEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return processPullRequestEvent(event.Token, payload, opts)
+		})
 	case *github.PullRequestReviewEvent:
-		return processPullRequestReviewEvent(payload), nil
+		return traceStep("processPullRequestReviewEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return processPullRequestReviewEvent(payload, opts), nil
+		})
 	case *github.PullRequestReviewCommentEvent:
-		return processPullRequestReviewCommentEvent(payload), nil
+		return traceStep("processPullRequestReviewCommentEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return processPullRequestReviewCommentEvent(payload, opts), nil
+		})
 	case *github.PullRequestTargetEvent:
-		return processPullRequestTargetEvent(payload), nil
+		return traceStep("processPullRequestTargetEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return processPullRequestTargetEvent(event.Token, payload, opts)
+		})
 	case *github.StatusEvent:
-		return processStatusEvent(*event.Token, payload)
+		return traceStep("processStatusEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return withToken(event, func(token string) ([]*TargetEntity, error) {
+				return processStatusEvent(token, payload, opts)
+			})
+		})
 	case *github.WorkflowRunEvent:
-		return processWorkflowRunEvent(*event.Token, payload)
+		return traceStep("processWorkflowRunEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return withToken(event, func(token string) ([]*TargetEntity, error) {
+				return processWorkflowRunEvent(token, payload, opts)
+			})
+		})
+	case *github.CreateEvent:
+		return traceStep("processCreateEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return withToken(event, func(token string) ([]*TargetEntity, error) {
+				return processCreateEvent(token, payload, opts)
+			})
+		})
+	case *github.DeleteEvent:
+		return traceStep("processDeleteEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return withToken(event, func(token string) ([]*TargetEntity, error) {
+				return processDeleteEvent(token, payload, opts)
+			})
+		})
+	case *github.DeploymentEvent:
+		return traceStep("processDeploymentEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return withToken(event, func(token string) ([]*TargetEntity, error) {
+				return processDeploymentEvent(token, payload, opts)
+			})
+		})
+	case *github.DeploymentStatusEvent:
+		return traceStep("processDeploymentStatusEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return withToken(event, func(token string) ([]*TargetEntity, error) {
+				return processDeploymentStatusEvent(token, payload, opts)
+			})
+		})
+	case *github.DiscussionEvent:
+		return traceStep("processDiscussionEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return processDiscussionEvent(payload, opts), nil
+		})
+	case *github.ReleaseEvent:
+		return traceStep("processReleaseEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return withToken(event, func(token string) ([]*TargetEntity, error) {
+				return processReleaseEvent(token, payload, opts)
+			})
+		})
+	case *github.MilestoneEvent:
+		return traceStep("processMilestoneEvent", opts, func(opts *options) ([]*TargetEntity, error) {
+			return withToken(event, func(token string) ([]*TargetEntity, error) {
+				return processMilestoneEvent(token, payload, opts)
+			})
+		})
 	}
 
 	return nil, fmt.Errorf("unknown event payload type: %T", eventPayload)
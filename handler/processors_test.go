@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/google/go-github/v45/github"
@@ -42,6 +43,70 @@ func TestParseEvent(t *testing.T) {
 	assert.Equal(t, wantEvent, gotEvent)
 }
 
+func TestTargetEntityKind_String(t *testing.T) {
+	tests := map[string]struct {
+		kind    handler.TargetEntityKind
+		wantVal string
+	}{
+		"pull_request": {
+			kind:    handler.PullRequest,
+			wantVal: "pull",
+		},
+		"issue": {
+			kind:    handler.Issue,
+			wantVal: "issue",
+		},
+		"discussion": {
+			kind:    handler.Discussion,
+			wantVal: "discussion",
+		},
+		"unknown": {
+			kind:    handler.TargetEntityKind("commit"),
+			wantVal: "unknown(commit)",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.wantVal, test.kind.String())
+		})
+	}
+}
+
+func TestTargetEntity_MarshalJSON(t *testing.T) {
+	entity := &handler.TargetEntity{
+		Kind:   handler.Discussion,
+		Number: 42,
+		Owner:  "reviewpad",
+		Repo:   "reviewpad",
+	}
+
+	gotVal, err := json.Marshal(entity)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"Kind": "discussion", "Number": 42, "Owner": "reviewpad", "Repo": "reviewpad", "Derived": false, "Reason": ""}`, string(gotVal))
+}
+
+func TestTargetEntity_MarshalJSON_Failure(t *testing.T) {
+	entity := &handler.TargetEntity{
+		Kind:   handler.TargetEntityKind("commit"),
+		Number: 42,
+	}
+
+	gotVal, err := json.Marshal(entity)
+
+	assert.ErrorIs(t, err, handler.ErrUnknownTargetEntityKind)
+	assert.Nil(t, gotVal)
+}
+
+func TestTargetEntity_UnmarshalJSON_Failure(t *testing.T) {
+	entity := &handler.TargetEntity{}
+
+	err := json.Unmarshal([]byte(`{"Kind": "commit", "Number": 42}`), entity)
+
+	assert.ErrorIs(t, err, handler.ErrUnknownTargetEntityKind)
+}
+
 func TestProcessEvent_Failure(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
@@ -54,8 +119,27 @@ func TestProcessEvent_Failure(t *testing.T) {
 		},
 	)
 
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/issues", owner, repo),
+		func(req *http.Request) (*http.Response, error) {
+			return nil, fmt.Errorf("error")
+		},
+	)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/releases", owner, repo),
+		func(req *http.Request) (*http.Response, error) {
+			return nil, fmt.Errorf("error")
+		},
+	)
+
+	httpmock.RegisterResponder("POST", "https://api.github.com/graphql",
+		func(req *http.Request) (*http.Response, error) {
+			return nil, fmt.Errorf("error")
+		},
+	)
+
 	tests := map[string]struct {
 		event *handler.ActionEvent
+		opts  []handler.Option
 	}{
 		"pull_request": {
 			event: &handler.ActionEvent{
@@ -119,11 +203,163 @@ func TestProcessEvent_Failure(t *testing.T) {
 				}`)),
 			},
 		},
+		"milestone": {
+			event: &handler.ActionEvent{
+				EventName: github.String("milestone"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "closed",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"milestone": {
+						"number": 1
+					}
+				}`)),
+			},
+		},
+		"pull_request_merged": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "closed",
+					"number": 120,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"number": 120,
+						"merged": true,
+						"head": {
+							"ref": "develop"
+						},
+						"base": {
+							"ref": "main",
+							"repo": {
+								"name": "reviewpad",
+								"owner": {
+									"login": "reviewpad"
+								}
+							}
+						}
+					}
+				}`)),
+			},
+		},
+		"pull_request_linked_issues": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "opened",
+					"number": 130,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"number": 130
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithLinkedEntities(),
+			},
+		},
+		"projects_v2_item_malformed": {
+			event: &handler.ActionEvent{
+				EventName:    github.String("projects_v2_item"),
+				EventPayload: buildPayload([]byte(`{,}`)),
+			},
+		},
+		"projects_v2_item": {
+			event: &handler.ActionEvent{
+				EventName: github.String("projects_v2_item"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "edited",
+					"projects_v2_item": {
+						"content_node_id": "I_131",
+						"content_type": "Issue"
+					}
+				}`)),
+			},
+		},
+		"delete": {
+			event: &handler.ActionEvent{
+				EventName: github.String("delete"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"ref": "main",
+					"ref_type": "branch",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					}
+				}`)),
+			},
+		},
+		"deployment_status": {
+			event: &handler.ActionEvent{
+				EventName: github.String("deployment_status"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"deployment": {
+						"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+						"environment": "staging"
+					},
+					"deployment_status": {
+						"state": "success"
+					}
+				}`)),
+			},
+		},
+		"release": {
+			event: &handler.ActionEvent{
+				EventName: github.String("release"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "published",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"release": {
+						"tag_name": "v1.1.0"
+					}
+				}`)),
+			},
+		},
+		"discussion_comment": {
+			event: &handler.ActionEvent{
+				EventName:    github.String("discussion_comment"),
+				EventPayload: buildPayload([]byte(`{,}`)),
+			},
+		},
 	}
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			gotVal, gotErr := handler.ProcessEvent(test.event)
+			gotVal, gotErr := handler.ProcessEvent(test.event, test.opts...)
 
 			assert.Nil(t, gotVal)
 			assert.NotNil(t, gotErr)
@@ -131,6 +367,42 @@ func TestProcessEvent_Failure(t *testing.T) {
 	}
 }
 
+func TestProcessEvent_MissingToken(t *testing.T) {
+	tests := map[string]struct {
+		event *handler.ActionEvent
+	}{
+		"cron": {
+			event: &handler.ActionEvent{
+				EventName:  github.String("schedule"),
+				Repository: github.String("reviewpad/reviewpad"),
+			},
+		},
+		"status": {
+			event: &handler.ActionEvent{
+				EventName: github.String("status"),
+				EventPayload: buildPayload([]byte(`{
+					"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					}
+				}`)),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, gotErr := handler.ProcessEvent(test.event)
+
+			assert.Nil(t, gotVal)
+			assert.ErrorIs(t, gotErr, handler.ErrMissingToken)
+		})
+	}
+}
+
 func TestProcessEvent(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
@@ -143,6 +415,7 @@ func TestProcessEvent(t *testing.T) {
 				{
 					Number: github.Int(aladino.DefaultMockPrNum),
 					Base: &github.PullRequestBranch{
+						Ref: github.String("main"),
 						Repo: &github.Repository{
 							Name: github.String(repo),
 							Owner: &github.User{
@@ -157,6 +430,7 @@ func TestProcessEvent(t *testing.T) {
 				{
 					Number: github.Int(130),
 					Base: &github.PullRequestBranch{
+						Ref: github.String("develop"),
 						Repo: &github.Repository{
 							Name: github.String(repo),
 							Owner: &github.User{
@@ -166,21 +440,181 @@ func TestProcessEvent(t *testing.T) {
 					},
 					Head: &github.PullRequestBranch{
 						SHA: github.String("4bf24cc72f3a62423927a0ac8d70febad7c78e0k"),
+						Ref: github.String("feature"),
+					},
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			resp := httpmock.NewBytesResponse(200, b)
+
+			return resp, nil
+		},
+	)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/issues", owner, repo),
+		func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("state") != "open" {
+				return httpmock.NewStringResponse(422, ""), nil
+			}
+
+			if req.URL.Query().Get("milestone") == "" {
+				b, err := json.Marshal([]*github.Issue{
+					{Number: github.Int(aladino.DefaultMockPrNum)},
+					{Number: github.Int(120)},
+					{Number: github.Int(130)},
+					{Number: github.Int(131)},
+					{Number: github.Int(132)},
+					{Number: github.Int(134)},
+					{Number: github.Int(140)},
+					{Number: github.Int(141)},
+				})
+				if err != nil {
+					return nil, err
+				}
+
+				return httpmock.NewBytesResponse(200, b), nil
+			}
+
+			if req.URL.Query().Get("milestone") != "1" {
+				return httpmock.NewStringResponse(422, ""), nil
+			}
+
+			if req.URL.Query().Get("page") == "2" {
+				b, err := json.Marshal([]*github.Issue{
+					{
+						Number: github.Int(132),
+					},
+				})
+				if err != nil {
+					return nil, err
+				}
+
+				return httpmock.NewBytesResponse(200, b), nil
+			}
+
+			b, err := json.Marshal([]*github.Issue{
+				{
+					Number: github.Int(130),
+					PullRequestLinks: &github.PullRequestLinks{
+						URL: github.String(fmt.Sprintf("https://api.github.com/repos/%v/%v/pulls/130", owner, repo)),
 					},
 				},
+				{
+					Number: github.Int(131),
+				},
 			})
 			if err != nil {
 				return nil, err
 			}
 
 			resp := httpmock.NewBytesResponse(200, b)
+			resp.Header.Set("Link", fmt.Sprintf(`<https://api.github.com/repos/%v/%v/issues?milestone=1&page=2&state=open>; rel="next"`, owner, repo))
 
 			return resp, nil
 		},
 	)
 
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/explore-dev/docs/issues",
+		httpmock.NewStringResponder(200, `[{"number": 12}]`),
+	)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/releases", owner, repo),
+		httpmock.NewStringResponder(200, `[
+			{"tag_name": "v1.1.0", "created_at": "2022-08-20T10:00:00Z"},
+			{"tag_name": "v1.1.0-rc", "draft": true, "created_at": "2022-08-19T10:00:00Z"},
+			{"tag_name": "v1.0.0", "created_at": "2022-08-10T10:00:00Z"}
+		]`),
+	)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/compare/v1.0.0...v1.1.0", owner, repo),
+		httpmock.NewStringResponder(200, `{
+			"commits": [
+				{"sha": "1c5a7b4d3e2f"},
+				{"sha": "2d6b8c5e4f3a"},
+				{"sha": "3e7c9d6f5a4b"}
+			]
+		}`),
+	)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/commits/1c5a7b4d3e2f/pulls", owner, repo),
+		httpmock.NewStringResponder(200, `[{"number": 130, "merged_at": "2022-08-11T10:00:00Z"}]`),
+	)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/commits/2d6b8c5e4f3a/pulls", owner, repo),
+		httpmock.NewStringResponder(200, `[{"number": 130, "merged_at": "2022-08-11T10:00:00Z"}, {"number": 131}]`),
+	)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/commits/3e7c9d6f5a4b/pulls", owner, repo),
+		httpmock.NewStringResponder(200, `[{"number": 132, "merged_at": "2022-08-12T10:00:00Z"}]`),
+	)
+
+	httpmock.RegisterResponder("POST", "https://api.github.com/graphql",
+		func(req *http.Request) (*http.Response, error) {
+			var query struct {
+				Query     string
+				Variables map[string]interface{}
+			}
+			if err := json.NewDecoder(req.Body).Decode(&query); err != nil {
+				return nil, err
+			}
+
+			if strings.Contains(query.Query, "node(id: $id)") && query.Variables["id"] == "I_deleted" {
+				return httpmock.NewStringResponse(200, `{"data": {"node": null}}`), nil
+			}
+
+			if strings.Contains(query.Query, "node(id: $id)") {
+				return httpmock.NewStringResponse(200, fmt.Sprintf(`{
+					"data": {
+						"node": {
+							"number": %v,
+							"repository": {"name": "reviewpad", "owner": {"login": "reviewpad"}}
+						}
+					}
+				}`, strings.TrimPrefix(query.Variables["id"].(string), "I_"))), nil
+			}
+
+			if strings.Contains(query.Query, "closingIssuesReferences") {
+				return httpmock.NewStringResponse(200, `{
+					"data": {
+						"repository": {
+							"pullRequest": {
+								"body": "Fixes #131 and closes explore-dev/docs#12.\nRelated to #133.",
+								"closingIssuesReferences": {
+									"nodes": [
+										{"number": 131, "repository": {"name": "reviewpad", "owner": {"login": "reviewpad"}}},
+										{"number": 134, "repository": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}
+									]
+								}
+							}
+						}
+					}
+				}`), nil
+			}
+
+			return httpmock.NewStringResponse(200, `{
+				"data": {
+					"repository": {
+						"issue": {
+							"timelineItems": {
+								"nodes": [
+									{"subject": {"number": 140, "repository": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}},
+									{"willCloseTarget": true, "source": {"number": 141, "repository": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}},
+									{"willCloseTarget": false, "source": {"number": 142, "repository": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}}
+								]
+							}
+						}
+					}
+				}
+			}`), nil
+		},
+	)
+
 	tests := map[string]struct {
 		event   *handler.ActionEvent
+		opts    []handler.Option
 		wantVal []*handler.TargetEntity
 	}{
 		"pull_request": {
@@ -208,13 +642,13 @@ func TestProcessEvent(t *testing.T) {
 					Number: 130,
 					Owner:  owner,
 					Repo:   repo,
+					Reason: "subject of pull_request event",
 				},
 			},
 		},
-		"pull_request_target": {
+		"pull_request_without_token": {
 			event: &handler.ActionEvent{
-				EventName: github.String("pull_request_target"),
-				Token:     github.String("test-token"),
+				EventName: github.String("pull_request"),
 				EventPayload: buildPayload([]byte(`{
 					"action": "opened",
 					"number": 130,
@@ -226,7 +660,8 @@ func TestProcessEvent(t *testing.T) {
 					},
 					"pull_request": {
 						"body": "## Description",
-						"number": 130
+						"number": 130,
+						"state": "open"
 					}
 				}`)),
 			},
@@ -236,16 +671,16 @@ func TestProcessEvent(t *testing.T) {
 					Number: 130,
 					Owner:  owner,
 					Repo:   repo,
+					Reason: "subject of pull_request event",
 				},
 			},
 		},
-		"pull_request_review": {
+		"pull_request_synchronize_without_token": {
 			event: &handler.ActionEvent{
-				EventName: github.String("pull_request_review"),
-				Token:     github.String("test-token"),
+				EventName: github.String("pull_request"),
 				EventPayload: buildPayload([]byte(`{
-					"action": "opened",
-					"number": 130,
+					"action": "synchronize",
+					"number": 120,
 					"repository": {
 						"name": "reviewpad",
 						"owner": {
@@ -253,26 +688,44 @@ func TestProcessEvent(t *testing.T) {
 						}
 					},
 					"pull_request": {
-						"body": "## Description",
-						"number": 130
+						"number": 120,
+						"state": "open",
+						"head": {
+							"ref": "develop",
+							"repo": {
+								"full_name": "reviewpad/reviewpad"
+							}
+						},
+						"base": {
+							"ref": "main",
+							"repo": {
+								"full_name": "reviewpad/reviewpad",
+								"name": "reviewpad",
+								"owner": {
+									"login": "reviewpad"
+								}
+							}
+						}
 					}
 				}`)),
 			},
 			wantVal: []*handler.TargetEntity{
 				{
 					Kind:   handler.PullRequest,
-					Number: 130,
+					Number: 120,
 					Owner:  owner,
 					Repo:   repo,
+					Reason: "subject of pull_request event",
 				},
 			},
 		},
-		"pull_request_review_comment": {
+		"pull_request_merged_stacked": {
 			event: &handler.ActionEvent{
-				EventName: github.String("pull_request_review_comment"),
+				EventName: github.String("pull_request"),
 				Token:     github.String("test-token"),
 				EventPayload: buildPayload([]byte(`{
-					"action": "created",
+					"action": "closed",
+					"number": 120,
 					"repository": {
 						"name": "reviewpad",
 						"owner": {
@@ -280,89 +733,276 @@ func TestProcessEvent(t *testing.T) {
 						}
 					},
 					"pull_request": {
-						"body": "## Description",
-						"number": 130
+						"number": 120,
+						"state": "closed",
+						"merged": true,
+						"head": {
+							"ref": "develop",
+							"repo": {
+								"full_name": "reviewpad/reviewpad"
+							}
+						},
+						"base": {
+							"ref": "main",
+							"repo": {
+								"full_name": "reviewpad/reviewpad",
+								"name": "reviewpad",
+								"owner": {
+									"login": "reviewpad"
+								}
+							}
+						}
 					}
 				}`)),
 			},
 			wantVal: []*handler.TargetEntity{
 				{
-					Kind:   handler.PullRequest,
-					Number: 130,
-					Owner:  owner,
-					Repo:   repo,
+					Kind:    handler.PullRequest,
+					Number:  130,
+					Owner:   owner,
+					Repo:    repo,
+					Derived: true,
+					Reason:  "stacked on #120",
 				},
 			},
 		},
-		"cron": {
+		"pull_request_merged_stacked_with_merged": {
 			event: &handler.ActionEvent{
-				EventName:  github.String("schedule"),
-				Token:      github.String("test-token"),
-				Repository: github.String("reviewpad/reviewpad"),
+				EventName: github.String("pull_request"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "closed",
+					"number": 120,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"number": 120,
+						"state": "closed",
+						"merged": true,
+						"head": {
+							"ref": "develop",
+							"repo": {
+								"full_name": "reviewpad/reviewpad"
+							}
+						},
+						"base": {
+							"ref": "main",
+							"repo": {
+								"full_name": "reviewpad/reviewpad",
+								"name": "reviewpad",
+								"owner": {
+									"login": "reviewpad"
+								}
+							}
+						}
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithMergedEntities(),
 			},
 			wantVal: []*handler.TargetEntity{
 				{
 					Kind:   handler.PullRequest,
-					Number: 130,
+					Number: 120,
 					Owner:  owner,
 					Repo:   repo,
+					Reason: "subject of pull_request event",
 				},
 				{
-					Kind:   handler.PullRequest,
-					Number: aladino.DefaultMockPrNum,
-					Owner:  owner,
-					Repo:   repo,
+					Kind:    handler.PullRequest,
+					Number:  130,
+					Owner:   owner,
+					Repo:    repo,
+					Derived: true,
+					Reason:  "stacked on #120",
 				},
 			},
 		},
-		"workflow_run_match": {
+		"pull_request_synchronize_stacked": {
 			event: &handler.ActionEvent{
-				EventName: github.String("workflow_run"),
+				EventName: github.String("pull_request"),
 				Token:     github.String("test-token"),
 				EventPayload: buildPayload([]byte(`{
-					"action": "completed",
+					"action": "synchronize",
+					"number": 120,
 					"repository": {
 						"name": "reviewpad",
 						"owner": {
 							"login": "reviewpad"
 						}
 					},
-					"workflow_run": {
-						"head_sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"
+					"pull_request": {
+						"number": 120,
+						"state": "open",
+						"merged": false,
+						"head": {
+							"ref": "develop",
+							"repo": {
+								"full_name": "reviewpad/reviewpad"
+							}
+						},
+						"base": {
+							"ref": "main",
+							"repo": {
+								"full_name": "reviewpad/reviewpad",
+								"name": "reviewpad",
+								"owner": {
+									"login": "reviewpad"
+								}
+							}
+						}
 					}
 				}`)),
 			},
 			wantVal: []*handler.TargetEntity{
 				{
 					Kind:   handler.PullRequest,
-					Number: aladino.DefaultMockPrNum,
+					Number: 120,
 					Owner:  owner,
 					Repo:   repo,
+					Reason: "subject of pull_request event",
+				},
+				{
+					Kind:    handler.PullRequest,
+					Number:  130,
+					Owner:   owner,
+					Repo:    repo,
+					Derived: true,
+					Reason:  "stacked on #120",
 				},
 			},
 		},
-		"workflow_run_no_match": {
+		"pull_request_closed_not_merged": {
 			event: &handler.ActionEvent{
-				EventName: github.String("workflow_run"),
+				EventName: github.String("pull_request"),
 				Token:     github.String("test-token"),
 				EventPayload: buildPayload([]byte(`{
-					"action": "completed",
+					"action": "closed",
+					"number": 120,
 					"repository": {
 						"name": "reviewpad",
 						"owner": {
 							"login": "reviewpad"
 						}
 					},
-					"workflow_run": {
-						"head_sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0a"
+					"pull_request": {
+						"number": 120,
+						"state": "closed",
+						"merged": false,
+						"head": {
+							"ref": "develop",
+							"repo": {
+								"full_name": "reviewpad/reviewpad"
+							}
+						},
+						"base": {
+							"ref": "main",
+							"repo": {
+								"full_name": "reviewpad/reviewpad",
+								"name": "reviewpad",
+								"owner": {
+									"login": "reviewpad"
+								}
+							}
+						}
 					}
 				}`)),
 			},
 			wantVal: []*handler.TargetEntity{},
 		},
-		"issues": {
+		"pull_request_merged_from_fork": {
 			event: &handler.ActionEvent{
-				EventName: github.String("issues"),
+				EventName: github.String("pull_request"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "closed",
+					"number": 120,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"number": 120,
+						"state": "closed",
+						"merged": true,
+						"head": {
+							"ref": "develop",
+							"repo": {
+								"full_name": "fork/reviewpad"
+							}
+						},
+						"base": {
+							"ref": "main",
+							"repo": {
+								"full_name": "reviewpad/reviewpad",
+								"name": "reviewpad",
+								"owner": {
+									"login": "reviewpad"
+								}
+							}
+						}
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"pull_request_target_merged_stacked": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request_target"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "closed",
+					"number": 120,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"number": 120,
+						"state": "closed",
+						"merged": true,
+						"head": {
+							"ref": "develop",
+							"repo": {
+								"full_name": "reviewpad/reviewpad"
+							}
+						},
+						"base": {
+							"ref": "main",
+							"repo": {
+								"full_name": "reviewpad/reviewpad",
+								"name": "reviewpad",
+								"owner": {
+									"login": "reviewpad"
+								}
+							}
+						}
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:    handler.PullRequest,
+					Number:  130,
+					Owner:   owner,
+					Repo:    repo,
+					Derived: true,
+					Reason:  "stacked on #120",
+				},
+			},
+		},
+		"pull_request_linked_issues": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request"),
 				Token:     github.String("test-token"),
 				EventPayload: buildPayload([]byte(`{
 					"action": "opened",
@@ -373,28 +1013,148 @@ func TestProcessEvent(t *testing.T) {
 							"login": "reviewpad"
 						}
 					},
-					"issue": {
+					"pull_request": {
 						"body": "## Description",
 						"number": 130
 					}
 				}`)),
 			},
+			opts: []handler.Option{
+				handler.WithLinkedEntities(),
+			},
 			wantVal: []*handler.TargetEntity{
 				{
-					Kind:   handler.Issue,
+					Kind:   handler.PullRequest,
 					Number: 130,
 					Owner:  owner,
-					Repo:   owner,
+					Repo:   repo,
+					Reason: "subject of pull_request event",
+				},
+				{
+					Kind:    handler.Issue,
+					Number:  131,
+					Owner:   owner,
+					Repo:    repo,
+					Derived: true,
+					Reason:  "linked issue of #130",
+				},
+				{
+					Kind:    handler.Issue,
+					Number:  12,
+					Owner:   "explore-dev",
+					Repo:    "docs",
+					Derived: true,
+					Reason:  "linked issue of #130",
+				},
+				{
+					Kind:    handler.Issue,
+					Number:  134,
+					Owner:   owner,
+					Repo:    repo,
+					Derived: true,
+					Reason:  "linked issue of #130",
 				},
 			},
 		},
-		"issue_comment": {
+		"pull_request_linked_issues_without_token": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "opened",
+					"number": 130,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"body": "## Description",
+						"number": 130,
+						"state": "open"
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithLinkedEntities(),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of pull_request event",
+				},
+			},
+		},
+		"issue_comment_on_pull_request_linked_issues": {
 			event: &handler.ActionEvent{
 				EventName: github.String("issue_comment"),
 				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "created",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"issue": {
+						"number": 130,
+						"state": "open",
+						"pull_request": {
+							"url": "https://api.github.com/repos/reviewpad/reviewpad/pulls/130"
+						}
+					},
+					"comment": {
+						"body": "Lgtm"
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithLinkedEntities(),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.Issue,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of issue_comment event",
+				},
+				{
+					Kind:    handler.Issue,
+					Number:  131,
+					Owner:   owner,
+					Repo:    repo,
+					Derived: true,
+					Reason:  "linked issue of #130",
+				},
+				{
+					Kind:    handler.Issue,
+					Number:  12,
+					Owner:   "explore-dev",
+					Repo:    "docs",
+					Derived: true,
+					Reason:  "linked issue of #130",
+				},
+				{
+					Kind:    handler.Issue,
+					Number:  134,
+					Owner:   owner,
+					Repo:    repo,
+					Derived: true,
+					Reason:  "linked issue of #130",
+				},
+			},
+		},
+		"issues_linked_pull_requests": {
+			event: &handler.ActionEvent{
+				EventName: github.String("issues"),
+				Token:     github.String("test-token"),
 				EventPayload: buildPayload([]byte(`{
 					"action": "opened",
-					"number": 130,
 					"repository": {
 						"name": "reviewpad",
 						"owner": {
@@ -403,69 +1163,1524 @@ func TestProcessEvent(t *testing.T) {
 					},
 					"issue": {
 						"body": "## Description",
-						"number": 130
+						"number": 131
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithLinkedEntities(),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.Issue,
+					Number: 131,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of issues event",
+				},
+				{
+					Kind:    handler.PullRequest,
+					Number:  140,
+					Owner:   owner,
+					Repo:    repo,
+					Derived: true,
+					Reason:  "linked pull request of #131",
+				},
+				{
+					Kind:    handler.PullRequest,
+					Number:  141,
+					Owner:   owner,
+					Repo:    repo,
+					Derived: true,
+					Reason:  "linked pull request of #131",
+				},
+			},
+		},
+		"projects_v2_item_issue": {
+			event: &handler.ActionEvent{
+				EventName: github.String("projects_v2_item"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "edited",
+					"projects_v2_item": {
+						"id": 1,
+						"node_id": "PVTI_lADOBp",
+						"project_node_id": "PVT_kwDOBp",
+						"content_node_id": "I_131",
+						"content_type": "Issue"
+					},
+					"organization": {
+						"login": "reviewpad"
 					}
 				}`)),
 			},
 			wantVal: []*handler.TargetEntity{
 				{
 					Kind:   handler.Issue,
+					Number: 131,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "content of projects_v2_item event",
+				},
+			},
+		},
+		"projects_v2_item_pull_request": {
+			event: &handler.ActionEvent{
+				EventName: github.String("projects_v2_item"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "edited",
+					"projects_v2_item": {
+						"id": 1,
+						"node_id": "PVTI_lADOBp",
+						"project_node_id": "PVT_kwDOBp",
+						"content_node_id": "I_130",
+						"content_type": "PullRequest"
+					},
+					"organization": {
+						"login": "reviewpad"
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
 					Number: 130,
 					Owner:  owner,
 					Repo:   repo,
+					Reason: "content of projects_v2_item event",
 				},
 			},
 		},
-		"status_match": {
+		"projects_v2_item_deleted_content": {
 			event: &handler.ActionEvent{
-				EventName: github.String("status"),
+				EventName: github.String("projects_v2_item"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "edited",
+					"projects_v2_item": {
+						"id": 1,
+						"node_id": "PVTI_lADOBp",
+						"project_node_id": "PVT_kwDOBp",
+						"content_node_id": "I_deleted",
+						"content_type": "Issue"
+					},
+					"organization": {
+						"login": "reviewpad"
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"projects_v2_item_draft_issue": {
+			event: &handler.ActionEvent{
+				EventName: github.String("projects_v2_item"),
 				Token:     github.String("test-token"),
 				EventPayload: buildPayload([]byte(`{
+					"action": "edited",
+					"projects_v2_item": {
+						"id": 1,
+						"node_id": "PVTI_lADOBp",
+						"project_node_id": "PVT_kwDOBp",
+						"content_node_id": "DI_1",
+						"content_type": "DraftIssue"
+					},
+					"organization": {
+						"login": "reviewpad"
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"pull_request_allowed_action": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "opened",
+					"number": 130,
 					"repository": {
 						"name": "reviewpad",
 						"owner": {
 							"login": "reviewpad"
 						}
 					},
-                    "pull_request": {
-                        "number": 6
-                    },
-					"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"
+					"pull_request": {
+						"body": "## Description",
+						"number": 130
+					}
 				}`)),
 			},
+			opts: []handler.Option{
+				handler.WithAllowedActions("pull_request", "opened", "synchronize", "reopened"),
+			},
 			wantVal: []*handler.TargetEntity{
 				{
 					Kind:   handler.PullRequest,
-					Number: aladino.DefaultMockPrNum,
+					Number: 130,
 					Owner:  owner,
 					Repo:   repo,
+					Reason: "subject of pull_request event",
 				},
 			},
 		},
-		"status_no_match": {
+		"pull_request_not_allowed_action": {
 			event: &handler.ActionEvent{
-				EventName: github.String("status"),
+				EventName: github.String("pull_request"),
 				Token:     github.String("test-token"),
 				EventPayload: buildPayload([]byte(`{
+					"action": "labeled",
+					"number": 130,
 					"repository": {
 						"name": "reviewpad",
 						"owner": {
 							"login": "reviewpad"
 						}
 					},
-					"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0a"
+					"pull_request": {
+						"body": "## Description",
+						"number": 130
+					}
 				}`)),
 			},
+			opts: []handler.Option{
+				handler.WithAllowedActions("pull_request", "opened", "synchronize", "reopened"),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"pull_request_denied_action": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "labeled",
+					"number": 130,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"body": "## Description",
+						"number": 130
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithDeniedActions("pull_request", "labeled", "edited"),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"pull_request_not_denied_action": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "opened",
+					"number": 130,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"body": "## Description",
+						"number": 130
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithDeniedActions("pull_request", "labeled", "edited"),
+				handler.WithAllowedActions("issues", "opened"),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of pull_request event",
+				},
+			},
+		},
+		"pull_request_target": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request_target"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "opened",
+					"number": 130,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"body": "## Description",
+						"number": 130
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of pull_request_target event",
+				},
+			},
+		},
+		"pull_request_review": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request_review"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "opened",
+					"number": 130,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"body": "## Description",
+						"number": 130
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of pull_request_review event",
+				},
+			},
+		},
+		"pull_request_review_comment": {
+			event: &handler.ActionEvent{
+				EventName: github.String("pull_request_review_comment"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "created",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"pull_request": {
+						"body": "## Description",
+						"number": 130
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of pull_request_review_comment event",
+				},
+			},
+		},
+		"cron": {
+			event: &handler.ActionEvent{
+				EventName:  github.String("schedule"),
+				Token:      github.String("test-token"),
+				Repository: github.String("reviewpad/reviewpad"),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "cron sweep",
+				},
+				{
+					Kind:   handler.PullRequest,
+					Number: aladino.DefaultMockPrNum,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "cron sweep",
+				},
+			},
+		},
+		"workflow_run_match": {
+			event: &handler.ActionEvent{
+				EventName: github.String("workflow_run"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "completed",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"workflow_run": {
+						"head_sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: aladino.DefaultMockPrNum,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "head SHA of workflow_run event",
+				},
+			},
+		},
+		"workflow_run_no_match": {
+			event: &handler.ActionEvent{
+				EventName: github.String("workflow_run"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "completed",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"workflow_run": {
+						"head_sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0a"
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"issues": {
+			event: &handler.ActionEvent{
+				EventName: github.String("issues"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "opened",
+					"number": 130,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"issue": {
+						"body": "## Description",
+						"number": 130
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.Issue,
+					Number: 130,
+					Owner:  owner,
+					Repo:   owner,
+					Reason: "subject of issues event",
+				},
+			},
+		},
+		"issue_comment": {
+			event: &handler.ActionEvent{
+				EventName: github.String("issue_comment"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "opened",
+					"number": 130,
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"issue": {
+						"body": "## Description",
+						"number": 130
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.Issue,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of issue_comment event",
+				},
+			},
+		},
+		"status_match": {
+			event: &handler.ActionEvent{
+				EventName: github.String("status"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+                    "pull_request": {
+                        "number": 6
+                    },
+					"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: aladino.DefaultMockPrNum,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "head SHA of status event",
+				},
+			},
+		},
+		"status_no_match": {
+			event: &handler.ActionEvent{
+				EventName: github.String("status"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0a"
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"create_branch_base": {
+			event: &handler.ActionEvent{
+				EventName: github.String("create"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"ref": "main",
+					"ref_type": "branch",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: aladino.DefaultMockPrNum,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "base or head branch main of create event",
+				},
+			},
+		},
+		"create_branch_head": {
+			event: &handler.ActionEvent{
+				EventName: github.String("create"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"ref": "feature",
+					"ref_type": "branch",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "base or head branch feature of create event",
+				},
+			},
+		},
+		"create_tag": {
+			event: &handler.ActionEvent{
+				EventName: github.String("create"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"ref": "main",
+					"ref_type": "tag",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"delete_branch_base": {
+			event: &handler.ActionEvent{
+				EventName: github.String("delete"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"ref": "develop",
+					"ref_type": "branch",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "base or head branch develop of delete event",
+				},
+			},
+		},
+		"delete_branch_no_match": {
+			event: &handler.ActionEvent{
+				EventName: github.String("delete"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"ref": "release",
+					"ref_type": "branch",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"delete_tag": {
+			event: &handler.ActionEvent{
+				EventName: github.String("delete"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"ref": "v1.0.0",
+					"ref_type": "tag",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"deployment_match_sha": {
+			event: &handler.ActionEvent{
+				EventName: github.String("deployment"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"deployment": {
+						"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+						"ref": "main",
+						"environment": "staging"
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: aladino.DefaultMockPrNum,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "head SHA of deployment event",
+				},
+			},
+		},
+		"deployment_match_ref": {
+			event: &handler.ActionEvent{
+				EventName: github.String("deployment"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"deployment": {
+						"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0a",
+						"ref": "feature",
+						"environment": "staging"
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "head ref of deployment event",
+				},
+			},
+		},
+		"deployment_no_match": {
+			event: &handler.ActionEvent{
+				EventName: github.String("deployment"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"deployment": {
+						"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0a",
+						"ref": "main",
+						"environment": "staging"
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"deployment_filtered_environment": {
+			event: &handler.ActionEvent{
+				EventName: github.String("deployment"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"deployment": {
+						"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+						"ref": "main",
+						"environment": "production"
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithDeploymentEnvironments("staging"),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"deployment_status_match": {
+			event: &handler.ActionEvent{
+				EventName: github.String("deployment_status"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"deployment": {
+						"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+						"ref": "main",
+						"environment": "staging"
+					},
+					"deployment_status": {
+						"state": "success",
+						"environment": "staging"
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithDeploymentEnvironments("staging"),
+				handler.WithDeploymentStates("success"),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: aladino.DefaultMockPrNum,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "head SHA of deployment_status event",
+				},
+			},
+		},
+		"deployment_status_filtered_environment": {
+			event: &handler.ActionEvent{
+				EventName: github.String("deployment_status"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"deployment": {
+						"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+						"ref": "main",
+						"environment": "production"
+					},
+					"deployment_status": {
+						"state": "success",
+						"environment": "production"
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithDeploymentEnvironments("staging"),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"deployment_status_filtered_state": {
+			event: &handler.ActionEvent{
+				EventName: github.String("deployment_status"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"deployment": {
+						"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+						"ref": "main",
+						"environment": "staging"
+					},
+					"deployment_status": {
+						"state": "pending",
+						"environment": "staging"
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithDeploymentStates("success"),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"discussion": {
+			event: &handler.ActionEvent{
+				EventName: github.String("discussion"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "created",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"discussion": {
+						"body": "## Description",
+						"number": 42
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.Discussion,
+					Number: 42,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of discussion event",
+				},
+			},
+		},
+		"discussion_comment": {
+			event: &handler.ActionEvent{
+				EventName: github.String("discussion_comment"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "created",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"discussion": {
+						"body": "## Description",
+						"number": 42
+					},
+					"comment": {
+						"body": "Lgtm",
+						"parent_id": null
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.Discussion,
+					Number: 42,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of discussion_comment event",
+				},
+			},
+		},
+		"release": {
+			event: &handler.ActionEvent{
+				EventName: github.String("release"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "published",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"release": {
+						"tag_name": "v1.1.0",
+						"created_at": "2022-08-20T10:00:00Z"
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "merged in release v1.1.0",
+				},
+				{
+					Kind:   handler.PullRequest,
+					Number: 132,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "merged in release v1.1.0",
+				},
+			},
+		},
+		"release_max_commits": {
+			event: &handler.ActionEvent{
+				EventName: github.String("release"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "published",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"release": {
+						"tag_name": "v1.1.0",
+						"created_at": "2022-08-20T10:00:00Z"
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithReleaseMaxCommits(2),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "merged in release v1.1.0",
+				},
+			},
+		},
+		"release_max_commits_zero": {
+			event: &handler.ActionEvent{
+				EventName: github.String("release"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "published",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"release": {
+						"tag_name": "v1.1.0",
+						"created_at": "2022-08-20T10:00:00Z"
+					}
+				}`)),
+			},
+			opts: []handler.Option{
+				handler.WithReleaseMaxCommits(0),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "merged in release v1.1.0",
+				},
+				{
+					Kind:   handler.PullRequest,
+					Number: 132,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "merged in release v1.1.0",
+				},
+			},
+		},
+		"release_first": {
+			event: &handler.ActionEvent{
+				EventName: github.String("release"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "published",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"release": {
+						"tag_name": "v1.0.0",
+						"created_at": "2022-08-10T10:00:00Z"
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"milestone": {
+			event: &handler.ActionEvent{
+				EventName: github.String("milestone"),
+				Token:     github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{
+					"action": "closed",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"milestone": {
+						"number": 1
+					}
+				}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "in milestone #1",
+				},
+				{
+					Kind:   handler.Issue,
+					Number: 131,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "in milestone #1",
+				},
+				{
+					Kind:   handler.Issue,
+					Number: 132,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "in milestone #1",
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.ProcessEvent(test.event, test.opts...)
+
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, test.wantVal, gotVal)
+		})
+	}
+}
+
+func TestProcessEvent_SelfInflicted(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/user",
+		httpmock.NewStringResponder(200, `{"login": "reviewpad-user"}`),
+	)
+
+	payloads := map[string]string{
+		"issues":                      `"issue": {"number": 130}`,
+		"issue_comment":               `"issue": {"number": 130}, "comment": {"body": "Lgtm"}`,
+		"pull_request":                `"pull_request": {"number": 130}`,
+		"pull_request_review":         `"pull_request": {"number": 130}, "review": {"state": "approved"}`,
+		"pull_request_review_comment": `"pull_request": {"number": 130}, "comment": {"body": "Lgtm"}`,
+		"pull_request_target":         `"pull_request": {"number": 130}`,
+		"status":                      `"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"`,
+		"workflow_run":                `"workflow_run": {"head_sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"}`,
+		"milestone":                   `"milestone": {"number": 1}`,
+		"discussion":                  `"discussion": {"number": 42}`,
+		"discussion_comment":          `"discussion": {"number": 42}, "comment": {"body": "Lgtm"}`,
+		"deployment":                  `"deployment": {"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"}`,
+		"deployment_status":           `"deployment": {"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"}, "deployment_status": {"state": "success"}`,
+		"create":                      `"ref": "main", "ref_type": "branch"`,
+		"delete":                      `"ref": "main", "ref_type": "branch"`,
+		"release":                     `"release": {"tag_name": "v1.1.0"}`,
+		"projects_v2_item":            `"projects_v2_item": {"content_node_id": "I_130", "content_type": "Issue"}`,
+	}
+
+	senders := map[string]struct {
+		sender string
+		opts   []handler.Option
+	}{
+		"bot": {
+			sender: "renovate[bot]",
+		},
+		"bot_login": {
+			sender: "reviewpad-bot",
+			opts: []handler.Option{
+				handler.WithBotLogins("reviewpad-bot"),
+			},
+		},
+		"bot_login_without_detection": {
+			sender: "reviewpad[bot]",
+			opts: []handler.Option{
+				handler.WithoutBotSenderDetection(),
+				handler.WithBotLogins("reviewpad[bot]"),
+			},
+		},
+		"token_identity": {
+			sender: "reviewpad-user",
+		},
+	}
+
+	for senderName, sender := range senders {
+		for eventName, payload := range payloads {
+			t.Run(fmt.Sprintf("%v_%v", eventName, senderName), func(t *testing.T) {
+				event := &handler.ActionEvent{
+					EventName: github.String(eventName),
+					Token:     github.String("test-token"),
+					EventPayload: buildPayload([]byte(fmt.Sprintf(`{
+						"action": "created",
+						"repository": {
+							"name": "reviewpad",
+							"owner": {
+								"login": "reviewpad"
+							}
+						},
+						"sender": {
+							"login": "%v"
+						},
+						%v
+					}`, sender.sender, payload))),
+				}
+
+				gotVal, err := handler.ProcessEvent(event, sender.opts...)
+
+				assert.Nil(t, err)
+				assert.Equal(t, []*handler.TargetEntity{}, gotVal)
+			})
+		}
+	}
+}
+
+func TestProcessEvent_NotSelfInflicted(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/user",
+		httpmock.NewStringResponder(200, `{"login": "reviewpad-user"}`),
+	)
+
+	tests := map[string]struct {
+		sender string
+		token  string
+		opts   []handler.Option
+	}{
+		"user": {
+			sender: "john",
+			token:  "test-token",
+			opts: []handler.Option{
+				handler.WithBotLogins("reviewpad[bot]"),
+			},
+		},
+		"bot_without_detection": {
+			sender: "dependabot[bot]",
+			token:  "test-token",
+			opts: []handler.Option{
+				handler.WithoutBotSenderDetection(),
+				handler.WithBotLogins("reviewpad[bot]"),
+			},
+		},
+		"token_user_without_detection": {
+			sender: "reviewpad-user",
+			token:  "test-token",
+			opts: []handler.Option{
+				handler.WithoutTokenIdentityDetection(),
+			},
+		},
+		// The user of installation tokens is not fetched, since they can not access it.
+		"installation_token": {
+			sender: "reviewpad-user",
+			token:  "ghs_test-token",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			event := &handler.ActionEvent{
+				EventName: github.String("pull_request"),
+				Token:     github.String(test.token),
+				EventPayload: buildPayload([]byte(fmt.Sprintf(`{
+					"action": "opened",
+					"repository": {
+						"name": "reviewpad",
+						"owner": {
+							"login": "reviewpad"
+						}
+					},
+					"sender": {
+						"login": "%v"
+					},
+					"pull_request": {
+						"number": 130,
+						"state": "open"
+					}
+				}`, test.sender))),
+			}
+			wantVal := []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  "reviewpad",
+					Repo:   "reviewpad",
+					Reason: "subject of pull_request event",
+				},
+			}
+
+			gotVal, err := handler.ProcessEvent(event, test.opts...)
+
+			assert.Nil(t, err)
+			assert.Equal(t, wantVal, gotVal)
+		})
+	}
+}
+
+func TestProcessEvent_States(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	owner := "reviewpad"
+	repo := "reviewpad"
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/pulls", owner, repo),
+		httpmock.NewStringResponder(200, `[
+			{"number": 6, "state": "open", "base": {"repo": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}},
+			{"number": 130, "state": "open", "locked": true, "base": {"repo": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}}
+		]`),
+	)
+
+	// The state of the targets is taken from the event payload or from the
+	// responses they were fetched with, without listing the open issues.
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://api.github.com/repos/%v/%v/issues", owner, repo),
+		func(req *http.Request) (*http.Response, error) {
+			return nil, fmt.Errorf("unexpected listing of the open issues")
+		},
+	)
+
+	httpmock.RegisterResponder("POST", "https://api.github.com/graphql",
+		httpmock.NewStringResponder(200, `{
+			"data": {
+				"node": {
+					"number": 7,
+					"state": "MERGED",
+					"locked": false,
+					"repository": {"name": "reviewpad", "owner": {"login": "reviewpad"}}
+				}
+			}
+		}`),
+	)
+
+	cronEvent := &handler.ActionEvent{
+		EventName:  github.String("schedule"),
+		Token:      github.String("test-token"),
+		Repository: github.String("reviewpad/reviewpad"),
+	}
+
+	closedIssueEvent := &handler.ActionEvent{
+		EventName: github.String("issues"),
+		Token:     github.String("test-token"),
+		EventPayload: buildPayload([]byte(`{
+			"action": "closed",
+			"repository": {
+				"name": "reviewpad",
+				"owner": {
+					"login": "reviewpad"
+				}
+			},
+			"issue": {
+				"number": 8,
+				"state": "closed"
+			}
+		}`)),
+	}
+
+	mergedPullRequestEvent := &handler.ActionEvent{
+		EventName: github.String("projects_v2_item"),
+		Token:     github.String("test-token"),
+		EventPayload: buildPayload([]byte(`{
+			"action": "edited",
+			"projects_v2_item": {
+				"content_node_id": "PR_7",
+				"content_type": "PullRequest"
+			}
+		}`)),
+	}
+
+	// Comments on pull requests are delivered with the pull request as an issue.
+	pullRequestCommentEvent := &handler.ActionEvent{
+		EventName: github.String("issue_comment"),
+		EventPayload: buildPayload([]byte(`{
+			"action": "created",
+			"repository": {
+				"name": "reviewpad",
+				"owner": {
+					"login": "reviewpad"
+				}
+			},
+			"issue": {
+				"number": 9,
+				"state": "open",
+				"pull_request": {"merged_at": null}
+			},
+			"comment": {
+				"body": "Lgtm"
+			}
+		}`)),
+	}
+
+	tests := map[string]struct {
+		event   *handler.ActionEvent
+		opts    []handler.Option
+		wantVal []*handler.TargetEntity
+	}{
+		"pull_request_comment_without_token": {
+			event: pullRequestCommentEvent,
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.Issue,
+					Number: 9,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of issue_comment event",
+				},
+			},
+		},
+		"locked_skipped": {
+			event: cronEvent,
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 6,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "cron sweep",
+				},
+			},
+		},
+		"locked_included": {
+			event: cronEvent,
+			opts: []handler.Option{
+				handler.WithLockedEntities(),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 6,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "cron sweep",
+				},
+				{
+					Kind:   handler.PullRequest,
+					Number: 130,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "cron sweep",
+				},
+			},
+		},
+		"closed_skipped": {
+			event:   closedIssueEvent,
+			wantVal: []*handler.TargetEntity{},
+		},
+		"closed_included": {
+			event: closedIssueEvent,
+			opts: []handler.Option{
+				handler.WithClosedEntities(),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.Issue,
+					Number: 8,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "subject of issues event",
+				},
+			},
+		},
+		"merged_skipped": {
+			event:   mergedPullRequestEvent,
 			wantVal: []*handler.TargetEntity{},
 		},
+		"merged_skipped_with_closed": {
+			event: mergedPullRequestEvent,
+			opts: []handler.Option{
+				handler.WithClosedEntities(),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"merged_included": {
+			event: mergedPullRequestEvent,
+			opts: []handler.Option{
+				handler.WithMergedEntities(),
+			},
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 7,
+					Owner:  owner,
+					Repo:   repo,
+					Reason: "content of projects_v2_item event",
+				},
+			},
+		},
 	}
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			gotVal, err := handler.ProcessEvent(test.event)
+			gotVal, err := handler.ProcessEvent(test.event, test.opts...)
 
 			assert.Nil(t, err)
 			assert.ElementsMatch(t, test.wantVal, gotVal)
 		})
 	}
 }
+
+func TestProcessEvent_ForkPullRequests(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// The pull requests #1 and #2 have a head branch named feature, but the head branch of #2
+	// is in a fork, as is the head branch of #3, named main.
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/pulls",
+		httpmock.NewStringResponder(http.StatusOK, `[
+			{
+				"number": 2,
+				"state": "open",
+				"head": {"ref": "feature", "sha": "sha-2", "repo": {"full_name": "contributor/reviewpad"}},
+				"base": {"ref": "develop", "repo": {"full_name": "reviewpad/reviewpad", "name": "reviewpad", "owner": {"login": "reviewpad"}}}
+			},
+			{
+				"number": 1,
+				"state": "open",
+				"head": {"ref": "feature", "sha": "sha-1", "repo": {"full_name": "reviewpad/reviewpad"}},
+				"base": {"ref": "main", "repo": {"full_name": "reviewpad/reviewpad", "name": "reviewpad", "owner": {"login": "reviewpad"}}}
+			},
+			{
+				"number": 3,
+				"state": "open",
+				"head": {"ref": "main", "sha": "sha-3", "repo": {"full_name": "contributor/reviewpad"}},
+				"base": {"ref": "release", "repo": {"full_name": "reviewpad/reviewpad", "name": "reviewpad", "owner": {"login": "reviewpad"}}}
+			}
+		]`),
+	)
+
+	repository := `"repository": {"name": "reviewpad", "full_name": "reviewpad/reviewpad", "owner": {"login": "reviewpad"}}`
+
+	tests := map[string]struct {
+		event   *handler.ActionEvent
+		opts    []handler.Option
+		wantVal []*handler.TargetEntity
+	}{
+		"create_branch_head": {
+			event: &handler.ActionEvent{
+				EventName:    github.String("create"),
+				Token:        github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{"ref": "feature", "ref_type": "branch", ` + repository + `}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{Kind: handler.PullRequest, Number: 1, Owner: "reviewpad", Repo: "reviewpad", Reason: "base or head branch feature of create event"},
+			},
+		},
+		"delete_branch_head": {
+			event: &handler.ActionEvent{
+				EventName:    github.String("delete"),
+				Token:        github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{"ref": "feature", "ref_type": "branch", ` + repository + `}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{Kind: handler.PullRequest, Number: 1, Owner: "reviewpad", Repo: "reviewpad", Reason: "base or head branch feature of delete event"},
+			},
+		},
+		"delete_branch_base_of_fork": {
+			event: &handler.ActionEvent{
+				EventName:    github.String("delete"),
+				Token:        github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{"ref": "develop", "ref_type": "branch", ` + repository + `}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{Kind: handler.PullRequest, Number: 2, Owner: "reviewpad", Repo: "reviewpad", Reason: "base or head branch develop of delete event"},
+			},
+		},
+		"deployment_match_ref": {
+			event: &handler.ActionEvent{
+				EventName:    github.String("deployment"),
+				Token:        github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{"deployment": {"sha": "sha-deployed", "ref": "feature", "environment": "staging"}, ` + repository + `}`)),
+			},
+			wantVal: []*handler.TargetEntity{
+				{Kind: handler.PullRequest, Number: 1, Owner: "reviewpad", Repo: "reviewpad", Reason: "head ref of deployment event"},
+			},
+		},
+		"deployment_ref_of_fork": {
+			event: &handler.ActionEvent{
+				EventName:    github.String("deployment"),
+				Token:        github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{"deployment": {"sha": "sha-deployed", "ref": "main", "environment": "staging"}, ` + repository + `}`)),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+		"deployment_status_ref_of_fork": {
+			event: &handler.ActionEvent{
+				EventName:    github.String("deployment_status"),
+				Token:        github.String("test-token"),
+				EventPayload: buildPayload([]byte(`{"deployment": {"sha": "sha-deployed", "ref": "main", "environment": "staging"}, "deployment_status": {"state": "success"}, ` + repository + `}`)),
+			},
+			opts: []handler.Option{
+				handler.WithDeploymentStates("success"),
+			},
+			wantVal: []*handler.TargetEntity{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.ProcessEvent(test.event, test.opts...)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantVal, gotVal)
+		})
+	}
+}
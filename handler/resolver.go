@@ -0,0 +1,291 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// defaultGraphqlURL is the GraphQL endpoint used when the event does not provide one.
+	defaultGraphqlURL = "https://api.github.com/graphql"
+
+	// shaBatchMaxSize caps the number of commits resolved in a single query
+	// to keep it within the GraphQL resource limits.
+	shaBatchMaxSize = 50
+
+	// associatedPullRequestsLimit is the number of pull requests fetched for each commit.
+	associatedPullRequestsLimit = 10
+)
+
+// ShaResolver resolves commit SHAs to the pull requests associated with them through
+// the GitHub GraphQL API. The SHAs of the same repository that are resolved within a
+// short window are batched into a single query, which saves requests when a burst of
+// 'status' or 'workflow_run' events is processed by a long-running process.
+type ShaResolver struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	batches map[shaBatchKey]*shaBatch
+}
+
+type shaBatchKey struct {
+	url   string
+	token string
+	owner string
+	repo  string
+}
+
+type shaBatch struct {
+	shas []string
+	done chan struct{}
+
+	// ctx and client are the context and the client of the event that started the batch,
+	// so that the query is traced and counted in the metrics like the requests of the event.
+	ctx    context.Context
+	client *http.Client
+
+	pullRequests map[string][]*associatedPullRequest
+	err          error
+}
+
+type associatedPullRequest struct {
+	Number         int    `json:"number"`
+	State          string `json:"state"`
+	Locked         bool   `json:"locked"`
+	HeadRefOid     string `json:"headRefOid"`
+	BaseRepository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"baseRepository"`
+}
+
+// NewShaResolver returns a ShaResolver that waits for the given window before
+// resolving a batch of SHAs.
+func NewShaResolver(window time.Duration) *ShaResolver {
+	return &ShaResolver{
+		window:  window,
+		batches: make(map[shaBatchKey]*shaBatch),
+	}
+}
+
+// resolve returns the pull requests associated with the commit.
+func (r *ShaResolver) resolve(ctx context.Context, token, owner, repo, sha string, opts *options) ([]*associatedPullRequest, error) {
+	key := shaBatchKey{url: opts.graphqlURL, token: token, owner: owner, repo: repo}
+
+	r.mu.Lock()
+	batch, ok := r.batches[key]
+	if !ok {
+		batch = &shaBatch{
+			done: make(chan struct{}),
+			// The query is shared with the other events of the batch, thus it is
+			// not cancelled when the event that started the batch is.
+			ctx:    detachedContext{ctx},
+			client: &http.Client{Transport: newGithubTransport(opts)},
+		}
+		r.batches[key] = batch
+		time.AfterFunc(r.window, func() { r.flush(key, batch) })
+	}
+
+	if !contains(batch.shas, sha) {
+		batch.shas = append(batch.shas, sha)
+	}
+
+	full := len(batch.shas) >= shaBatchMaxSize
+	r.mu.Unlock()
+
+	if full {
+		go r.flush(key, batch)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-batch.done:
+	}
+
+	if batch.err != nil {
+		return nil, batch.err
+	}
+
+	return batch.pullRequests[sha], nil
+}
+
+// flush resolves the SHAs of the batch, unless it was already resolved.
+func (r *ShaResolver) flush(key shaBatchKey, batch *shaBatch) {
+	r.mu.Lock()
+	if r.batches[key] != batch {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.batches, key)
+	r.mu.Unlock()
+
+	ctx, canc := context.WithTimeout(batch.ctx, time.Minute*10)
+	defer canc()
+
+	DefaultLogger().Debug("resolving shas", Fields{"owner": key.owner, "repo": key.repo, "count": len(batch.shas)})
+
+	batch.pullRequests, batch.err = queryAssociatedPullRequests(ctx, batch.client, key, batch.shas)
+	close(batch.done)
+}
+
+// detachedContext keeps the values of its parent, such as its span, but not its deadline
+// nor its cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// queryAssociatedPullRequests fetches the pull requests associated with each of the commits in a single GraphQL query,
+// where each commit is an aliased field of the repository.
+func queryAssociatedPullRequests(ctx context.Context, client *http.Client, key shaBatchKey, shas []string) (map[string][]*associatedPullRequest, error) {
+	variables := map[string]interface{}{
+		"owner": key.owner,
+		"name":  key.repo,
+	}
+
+	var params, fields strings.Builder
+	for i, sha := range shas {
+		fmt.Fprintf(&params, ", $sha%v: GitObjectID!", i)
+		fmt.Fprintf(&fields, " commit%v: object(oid: $sha%v) { ... on Commit { associatedPullRequests(first: %v) { nodes { number state locked headRefOid baseRepository { name owner { login } } } } } }", i, i, associatedPullRequestsLimit)
+		variables[fmt.Sprintf("sha%v", i)] = sha
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     fmt.Sprintf("query($owner: String!, $name: String!%v) { repository(owner: $owner, name: $name) {%v } }", params.String(), fields.String()),
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, key.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "bearer "+key.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("graphql request failed: %v", resp.Status)
+	}
+
+	result := struct {
+		Data struct {
+			Repository map[string]*struct {
+				AssociatedPullRequests struct {
+					Nodes []*associatedPullRequest `json:"nodes"`
+				} `json:"associatedPullRequests"`
+			} `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode graphql response: %w", err)
+	}
+
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("graphql request failed: %v", result.Errors[0].Message)
+	}
+
+	pullRequests := make(map[string][]*associatedPullRequest)
+	for i, sha := range shas {
+		// Commits that do not exist in the repository are null.
+		if commit := result.Data.Repository[fmt.Sprintf("commit%v", i)]; commit != nil {
+			pullRequests[sha] = commit.AssociatedPullRequests.Nodes
+		}
+	}
+
+	return pullRequests, nil
+}
+
+// resolvePullRequestByHead looks for the open pull request whose head is at the given sha
+// among the pull requests associated with the commit.
+func resolvePullRequestByHead(token, owner, repo, sha, eventName string, opts *options) ([]*TargetEntity, error) {
+	ctx, canc := context.WithTimeout(opts.ctx, time.Minute*10)
+	defer canc()
+
+	// The batched query is shared by the events resolved at the same time, thus
+	// the span covers the wait for the batch instead of the query itself.
+	ctx, span := opts.tracer.Start(ctx, "ShaResolver.resolve", trace.WithAttributes(attribute.String("sha", sha)))
+	defer span.End()
+
+	associatedPRs, err := opts.shaResolver.resolve(ctx, token, owner, repo, sha, opts)
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+
+	// The pull requests are shared with the other events resolved in the same batch.
+	prs := append([]*associatedPullRequest{}, associatedPRs...)
+
+	opts.logger.Debug("resolved pull requests associated with sha", Fields{"sha": sha, "count": len(prs)})
+
+	// The most recent pull request is selected, as when listing the open pull requests.
+	sort.SliceStable(prs, func(i, j int) bool {
+		return prs[i].Number > prs[j].Number
+	})
+
+	var selected *TargetEntity
+	for _, pr := range prs {
+		target := &TargetEntity{
+			Kind:   PullRequest,
+			Number: pr.Number,
+			Owner:  pr.BaseRepository.Owner.Login,
+			Repo:   pr.BaseRepository.Name,
+		}
+
+		switch {
+		case target.Owner != owner || target.Repo != repo:
+			opts.drop(target, fmt.Sprintf("not in %v/%v", owner, repo))
+		case pr.State != "OPEN":
+			opts.drop(target, fmt.Sprintf("associated with SHA %v but %v", sha, strings.ToLower(pr.State)))
+		case pr.HeadRefOid != sha:
+			opts.drop(target, fmt.Sprintf("head %v does not match SHA %v", pr.HeadRefOid, sha))
+		case selected != nil:
+			opts.drop(target, fmt.Sprintf("head also matches but #%v was selected", selected.Number))
+		default:
+			target.Reason = fmt.Sprintf("head SHA of %v event", eventName)
+			opts.setState(target, pr.State, pr.Locked, false)
+			selected = target
+		}
+	}
+
+	if selected == nil {
+		opts.logger.Info("no pull request found with the head sha", Fields{"sha": sha})
+		return []*TargetEntity{}, nil
+	}
+
+	opts.logger.Info("found pull request", Fields{"number": selected.Number})
+
+	return []*TargetEntity{selected}, nil
+}
@@ -0,0 +1,285 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+const graphqlURL = "https://github.example.com/api/graphql"
+
+// registerAssociatedPullRequestsResponder mocks the GraphQL endpoint with the pull requests
+// associated with each commit and returns the number of SHAs in each query.
+func registerAssociatedPullRequestsResponder(associatedPullRequests map[string]string) func() []int {
+	var mu sync.Mutex
+	batches := make([]int, 0)
+
+	httpmock.RegisterResponder("POST", graphqlURL,
+		func(req *http.Request) (*http.Response, error) {
+			body := struct {
+				Query     string            `json:"query"`
+				Variables map[string]string `json:"variables"`
+			}{}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+
+			commits := make([]string, 0)
+			for name, sha := range body.Variables {
+				if !strings.HasPrefix(name, "sha") {
+					continue
+				}
+
+				commit := "null"
+				if prs, ok := associatedPullRequests[sha]; ok {
+					commit = fmt.Sprintf(`{"associatedPullRequests": {"nodes": [%v]}}`, prs)
+				}
+				commits = append(commits, fmt.Sprintf(`"commit%v": %v`, strings.TrimPrefix(name, "sha"), commit))
+			}
+
+			mu.Lock()
+			batches = append(batches, len(commits))
+			mu.Unlock()
+
+			return httpmock.NewStringResponse(http.StatusOK, fmt.Sprintf(`{"data": {"repository": {%v}}}`, strings.Join(commits, ", "))), nil
+		},
+	)
+
+	return func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return batches
+	}
+}
+
+func associatedPullRequest(number int, state, headSHA, repo string) string {
+	return fmt.Sprintf(`{"number": %v, "state": %q, "headRefOid": %q, "baseRepository": {"name": %q, "owner": {"login": "reviewpad"}}}`, number, state, headSHA, repo)
+}
+
+func statusEvent(sha string) *handler.ActionEvent {
+	return &handler.ActionEvent{
+		EventName:  github.String("status"),
+		Token:      github.String("test-token"),
+		QraphqlUrl: github.String(graphqlURL),
+		EventPayload: buildPayload([]byte(fmt.Sprintf(`{
+			"repository": {
+				"name": "reviewpad",
+				"owner": {
+					"login": "reviewpad"
+				}
+			},
+			"sha": %q
+		}`, sha))),
+	}
+}
+
+func TestProcessEvent_ShaResolver(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	getBatches := registerAssociatedPullRequestsResponder(map[string]string{
+		"sha-open":   associatedPullRequest(10, "OPEN", "sha-open", "reviewpad"),
+		"sha-closed": associatedPullRequest(11, "CLOSED", "sha-closed", "reviewpad"),
+		"sha-moved":  associatedPullRequest(12, "OPEN", "sha-other", "reviewpad"),
+		"sha-fork":   associatedPullRequest(13, "OPEN", "sha-fork", "docs"),
+		"sha-many": strings.Join([]string{
+			associatedPullRequest(14, "OPEN", "sha-many", "reviewpad"),
+			associatedPullRequest(16, "OPEN", "sha-many", "reviewpad"),
+			associatedPullRequest(15, "MERGED", "sha-many", "reviewpad"),
+		}, ", "),
+	})
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/issues",
+		httpmock.NewStringResponder(http.StatusOK, `[{"number": 10}, {"number": 12}, {"number": 14}, {"number": 16}]`),
+	)
+
+	tests := map[string]struct {
+		sha     string
+		wantVal []*handler.TargetEntity
+	}{
+		"open": {
+			sha: "sha-open",
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 10,
+					Owner:  "reviewpad",
+					Repo:   "reviewpad",
+					Reason: "head SHA of status event",
+				},
+			},
+		},
+		"closed": {
+			sha:     "sha-closed",
+			wantVal: []*handler.TargetEntity{},
+		},
+		"head_moved": {
+			sha:     "sha-moved",
+			wantVal: []*handler.TargetEntity{},
+		},
+		"other_repository": {
+			sha:     "sha-fork",
+			wantVal: []*handler.TargetEntity{},
+		},
+		"most_recent": {
+			sha: "sha-many",
+			wantVal: []*handler.TargetEntity{
+				{
+					Kind:   handler.PullRequest,
+					Number: 16,
+					Owner:  "reviewpad",
+					Repo:   "reviewpad",
+					Reason: "head SHA of status event",
+				},
+			},
+		},
+		"unknown_commit": {
+			sha:     "sha-unknown",
+			wantVal: []*handler.TargetEntity{},
+		},
+	}
+
+	resolver := handler.NewShaResolver(time.Millisecond * 100)
+
+	// The events are processed at the same time, as in a burst of status events.
+	var wg sync.WaitGroup
+	gotVals := make(map[string][]*handler.TargetEntity)
+	gotErrs := make(map[string]error)
+	var mu sync.Mutex
+	for name, test := range tests {
+		wg.Add(1)
+		go func(name, sha string) {
+			defer wg.Done()
+
+			gotVal, err := handler.ProcessEvent(statusEvent(sha), handler.WithShaResolver(resolver))
+
+			mu.Lock()
+			gotVals[name] = gotVal
+			gotErrs[name] = err
+			mu.Unlock()
+		}(name, test.sha)
+	}
+	wg.Wait()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Nil(t, gotErrs[name])
+			assert.Equal(t, test.wantVal, gotVals[name])
+		})
+	}
+
+	assert.Equal(t, []int{len(tests)}, getBatches())
+	assert.Equal(t, 0, httpmock.GetCallCountInfo()["GET https://api.github.com/repos/reviewpad/reviewpad/pulls"])
+}
+
+func TestProcessEvent_ShaResolver_Fallback(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", graphqlURL,
+		httpmock.NewStringResponder(http.StatusOK, `{"errors": [{"message": "Something went wrong"}]}`),
+	)
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/pulls",
+		httpmock.NewStringResponder(http.StatusOK, `[{"number": 10, "head": {"sha": "sha-open"}, "base": {"repo": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}}]`),
+	)
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/issues",
+		httpmock.NewStringResponder(http.StatusOK, `[{"number": 10}]`),
+	)
+
+	gotVal, err := handler.ProcessEvent(statusEvent("sha-open"), handler.WithShaResolver(handler.NewShaResolver(time.Millisecond)))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*handler.TargetEntity{
+		{
+			Kind:   handler.PullRequest,
+			Number: 10,
+			Owner:  "reviewpad",
+			Repo:   "reviewpad",
+			Reason: "head SHA of status event",
+		},
+	}, gotVal)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["POST "+graphqlURL])
+}
+
+func TestExplainEvent_ShaResolver(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerAssociatedPullRequestsResponder(map[string]string{
+		"sha-many": strings.Join([]string{
+			associatedPullRequest(14, "OPEN", "sha-many", "reviewpad"),
+			associatedPullRequest(16, "OPEN", "sha-many", "reviewpad"),
+			associatedPullRequest(15, "MERGED", "sha-many", "reviewpad"),
+			associatedPullRequest(13, "OPEN", "sha-other", "reviewpad"),
+		}, ", "),
+	})
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/issues",
+		httpmock.NewStringResponder(http.StatusOK, `[{"number": 13}, {"number": 14}, {"number": 16}]`),
+	)
+
+	gotVal, err := handler.ExplainEvent(statusEvent("sha-many"), handler.WithShaResolver(handler.NewShaResolver(time.Millisecond)))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*handler.Candidate{
+		{
+			Target:   &handler.TargetEntity{Kind: handler.PullRequest, Number: 16, Owner: "reviewpad", Repo: "reviewpad", Reason: "head SHA of status event"},
+			Included: true,
+		},
+		{
+			Target: &handler.TargetEntity{Kind: handler.PullRequest, Number: 15, Owner: "reviewpad", Repo: "reviewpad"},
+			Reason: "associated with SHA sha-many but merged",
+		},
+		{
+			Target: &handler.TargetEntity{Kind: handler.PullRequest, Number: 14, Owner: "reviewpad", Repo: "reviewpad"},
+			Reason: "head also matches but #16 was selected",
+		},
+		{
+			Target: &handler.TargetEntity{Kind: handler.PullRequest, Number: 13, Owner: "reviewpad", Repo: "reviewpad"},
+			Reason: "head sha-other does not match SHA sha-many",
+		},
+	}, gotVal.Candidates)
+}
+
+func TestProcessEvent_ShaResolver_WithTracerProvider(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerAssociatedPullRequestsResponder(map[string]string{
+		"sha-open": associatedPullRequest(10, "OPEN", "sha-open", "reviewpad"),
+	})
+
+	provider, exporter := newTracerProvider()
+	metrics := handler.NewMetrics()
+
+	_, err := handler.ProcessEvent(
+		statusEvent("sha-open"),
+		handler.WithShaResolver(handler.NewShaResolver(time.Millisecond)),
+		handler.WithTracerProvider(provider),
+		handler.WithMetrics(metrics),
+	)
+
+	assert.Nil(t, err)
+
+	// The batched query is made with the transport of the event that started the batch.
+	spans := spansByName(exporter)
+	querySpan, ok := spans["GitHub POST /api/graphql"]
+	assert.True(t, ok)
+	assert.Equal(t, spans["ShaResolver.resolve"].SpanContext.SpanID(), querySpan.Parent.SpanID())
+	assert.Contains(t, scrapeMetrics(t, metrics.Handler()), `host_event_handler_github_requests_total{code="200",method="POST"} 1`)
+}
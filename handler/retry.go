@@ -0,0 +1,228 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = time.Second * 30
+
+	// rateLimitLowQuota is the fraction of the rate limit below which the remaining quota is logged.
+	rateLimitLowQuota = 0.1
+)
+
+// RetryTransport is an http.RoundTripper that retries the requests to the GitHub API
+// that failed because of a rate limit or a transient server error.
+// Rate limited requests are retried after the time given by the Retry-After or
+// X-RateLimit-Reset headers, and server errors after a jittered exponential backoff.
+// Requests are not retried when the wait would exceed the deadline of their context.
+// For more information, visit: https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
+type RetryTransport struct {
+	// Base is the transport used to make the requests. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+	// MaxRetries is the maximum number of times a request is retried.
+	MaxRetries int
+	// MinBackoff is the wait before the first retry of a server error, doubled on each retry.
+	MinBackoff time.Duration
+	// MaxBackoff caps the wait between the retries of a server error.
+	MaxBackoff time.Duration
+	// Logger logs the retries and the low rate limit quota. Defaults to DefaultLogger.
+	Logger *Logger
+}
+
+// NewRetryTransport returns a RetryTransport over the given transport with the default retry policy.
+func NewRetryTransport(base http.RoundTripper) *RetryTransport {
+	return &RetryTransport{
+		Base:       base,
+		MaxRetries: defaultMaxRetries,
+		MinBackoff: defaultMinBackoff,
+		MaxBackoff: defaultMaxBackoff,
+	}
+}
+
+// RoundTrip sends the request, retrying it while the policy allows it.
+// The retries are clones of the request with a rewound body, as the request must not be modified.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attemptReq := req
+	for attempt := 0; ; attempt++ {
+		// Transport errors, such as an invalid certificate, are not retried.
+		resp, err := t.base().RoundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+
+		logQuota(t.logger(), resp)
+
+		if attempt >= t.MaxRetries || !rewindable(req) {
+			return resp, nil
+		}
+
+		wait, retry := t.retryAfter(resp, attempt)
+		if !retry || req.Context().Err() != nil {
+			return resp, nil
+		}
+
+		if deadline, ok := req.Context().Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			t.logger().Warn("not retrying request: the wait exceeds the deadline", requestFields(req), Fields{"wait": wait.String()})
+			return resp, nil
+		}
+
+		t.logger().Info("retrying request", requestFields(req), Fields{"wait": wait.String(), "status": resp.StatusCode})
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+
+		if req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewind request body: %w", err)
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+	}
+}
+
+func (t *RetryTransport) logger() *Logger {
+	if t.Logger != nil {
+		return t.Logger
+	}
+	return DefaultLogger()
+}
+
+func (t *RetryTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// retryAfter reports whether the request should be retried and how long to wait before retrying it.
+func (t *RetryTransport) retryAfter(resp *http.Response, attempt int) (time.Duration, bool) {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden:
+		if wait, ok := parseRetryAfter(resp); ok {
+			return wait, true
+		}
+
+		if wait, ok := parseRateLimitReset(resp); ok {
+			return wait, true
+		}
+
+		// Forbidden responses that are not caused by a rate limit are not retried.
+		if resp.StatusCode == http.StatusForbidden {
+			return 0, false
+		}
+
+		return t.backoff(attempt), true
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return t.backoff(attempt), true
+	default:
+		return 0, false
+	}
+}
+
+// backoff returns the exponential backoff of the attempt with a random jitter of up to half of it.
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	backoff := t.MinBackoff << attempt
+	if backoff > t.MaxBackoff || backoff <= 0 {
+		backoff = t.MaxBackoff
+	}
+
+	if half := int64(backoff / 2); half > 0 {
+		backoff = backoff/2 + time.Duration(rand.Int63n(half))
+	}
+
+	return backoff
+}
+
+// parseRetryAfter returns the wait in the Retry-After header, sent with secondary rate limits.
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	retryAfter := resp.Header.Get("Retry-After")
+	if retryAfter == "" {
+		return 0, false
+	}
+
+	seconds, err := strconv.Atoi(retryAfter)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// parseRateLimitReset returns the wait until the reset of an exhausted primary rate limit.
+func parseRateLimitReset(resp *http.Response) (time.Duration, bool) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	wait := time.Until(time.Unix(reset, 0))
+	if wait < 0 {
+		wait = 0
+	}
+
+	// The reset time has a precision of seconds.
+	return wait + time.Second, true
+}
+
+// logQuota logs the remaining quota of the rate limit when it is running low.
+func logQuota(logger *Logger, resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil || limit == 0 {
+		return
+	}
+
+	if float64(remaining) >= float64(limit)*rateLimitLowQuota {
+		return
+	}
+
+	reset := resp.Header.Get("X-RateLimit-Reset")
+	if resetUnix, err := strconv.ParseInt(reset, 10, 64); err == nil {
+		reset = time.Unix(resetUnix, 0).UTC().Format(time.RFC3339)
+	}
+
+	logger.Warn("low rate limit quota", Fields{"remaining": remaining, "limit": limit, "reset": reset})
+}
+
+// rewindable reports whether the request can be sent again.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func sleep(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+type retryResponse struct {
+	status  int
+	headers map[string]string
+}
+
+// newRetryServer returns a server that replies with the given responses in order,
+// and with the last one once they are exhausted, and records the bodies of the requests.
+func newRetryServer(t *testing.T, responses []retryResponse) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	bodies := make([]string, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		response := responses[len(responses)-1]
+		if len(bodies) <= len(responses) {
+			response = responses[len(bodies)-1]
+		}
+		mu.Unlock()
+
+		for key, value := range response.headers {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(response.status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return bodies
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := map[string]struct {
+		responses    []retryResponse
+		timeout      time.Duration
+		wantStatus   int
+		wantRequests int
+	}{
+		"success": {
+			responses:    []retryResponse{{status: http.StatusOK}},
+			wantStatus:   http.StatusOK,
+			wantRequests: 1,
+		},
+		"bad_gateway": {
+			responses: []retryResponse{
+				{status: http.StatusBadGateway},
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusOK},
+			},
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+		},
+		"internal_server_error_exhausted": {
+			responses:    []retryResponse{{status: http.StatusInternalServerError}},
+			wantStatus:   http.StatusInternalServerError,
+			wantRequests: 4,
+		},
+		"secondary_rate_limit": {
+			responses: []retryResponse{
+				{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "0"}},
+				{status: http.StatusOK},
+			},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+		"too_many_requests": {
+			responses: []retryResponse{
+				{status: http.StatusTooManyRequests},
+				{status: http.StatusOK},
+			},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+		"primary_rate_limit": {
+			responses: []retryResponse{
+				{status: http.StatusForbidden, headers: map[string]string{
+					"X-RateLimit-Limit":     "5000",
+					"X-RateLimit-Remaining": "0",
+					"X-RateLimit-Reset":     fmt.Sprint(time.Now().Add(-time.Second).Unix()),
+				}},
+				{status: http.StatusOK},
+			},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+		"forbidden": {
+			responses:    []retryResponse{{status: http.StatusForbidden}},
+			wantStatus:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+		"not_found": {
+			responses:    []retryResponse{{status: http.StatusNotFound}},
+			wantStatus:   http.StatusNotFound,
+			wantRequests: 1,
+		},
+		"wait_exceeds_deadline": {
+			responses: []retryResponse{
+				{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "60"}},
+				{status: http.StatusOK},
+			},
+			timeout:      time.Second,
+			wantStatus:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server, getBodies := newRetryServer(t, test.responses)
+
+			client := &http.Client{
+				Transport: &handler.RetryTransport{
+					MaxRetries: 3,
+					MinBackoff: time.Millisecond,
+					MaxBackoff: time.Millisecond * 10,
+				},
+			}
+
+			ctx := context.Background()
+			if test.timeout != 0 {
+				var canc context.CancelFunc
+				ctx, canc = context.WithTimeout(ctx, test.timeout)
+				defer canc()
+			}
+
+			req, err := http.NewRequestWithContext(ctx, "POST", server.URL, strings.NewReader("body"))
+			assert.Nil(t, err)
+
+			resp, err := client.Do(req)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantStatus, resp.StatusCode)
+
+			bodies := getBodies()
+			assert.Equal(t, test.wantRequests, len(bodies))
+			for _, body := range bodies {
+				assert.Equal(t, "body", body)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_RequestNotModified(t *testing.T) {
+	server, getBodies := newRetryServer(t, []retryResponse{
+		{status: http.StatusBadGateway},
+		{status: http.StatusOK},
+	})
+
+	transport := &handler.RetryTransport{
+		MaxRetries: 3,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond * 10,
+	}
+
+	req, err := http.NewRequest("POST", server.URL, strings.NewReader("body"))
+	assert.Nil(t, err)
+	body := req.Body
+
+	resp, err := transport.RoundTrip(req)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"body", "body"}, getBodies())
+	assert.Equal(t, body, req.Body)
+}
+
+func TestRetryTransport_Failure(t *testing.T) {
+	server, getBodies := newRetryServer(t, []retryResponse{
+		{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "1"}},
+		{status: http.StatusOK},
+	})
+
+	client := &http.Client{
+		Transport: handler.NewRetryTransport(nil),
+	}
+
+	ctx, canc := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*100, canc)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	assert.Nil(t, err)
+
+	resp, err := client.Do(req)
+
+	assert.NotNil(t, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, 1, len(getBodies()))
+}
+
+func TestProcessEvent_Retry(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/pulls",
+		httpmock.ResponderFromMultipleResponses([]*http.Response{
+			httpmock.NewStringResponse(http.StatusBadGateway, ""),
+			httpmock.NewStringResponse(http.StatusOK, `[{"number": 1, "base": {"repo": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}}]`),
+		}),
+	)
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/issues",
+		httpmock.NewStringResponder(http.StatusOK, `[{"number": 1}]`),
+	)
+
+	event := &handler.ActionEvent{
+		EventName:  github.String("schedule"),
+		Token:      github.String("test-token"),
+		Repository: github.String("reviewpad/reviewpad"),
+	}
+
+	gotVal, err := handler.ProcessEvent(event)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*handler.TargetEntity{
+		{
+			Kind:   handler.PullRequest,
+			Number: 1,
+			Owner:  "reviewpad",
+			Repo:   "reviewpad",
+			Reason: "cron sweep",
+		},
+	}, gotVal)
+	assert.Equal(t, 2, httpmock.GetCallCountInfo()["GET https://api.github.com/repos/reviewpad/reviewpad/pulls"])
+}
@@ -0,0 +1,170 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// maxWebhookPayloadSize is the maximum size of the payloads delivered by GitHub.
+// For more information, visit: https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#payload-cap
+const maxWebhookPayloadSize = 25 << 20
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrMissingWebhookSecret is returned for the deliveries to a server without a webhook secret,
+	// unless it is configured with InsecureSkipSignature.
+	ErrMissingWebhookSecret = errors.New("missing webhook secret")
+)
+
+// ServerConfig configures a Server.
+type ServerConfig struct {
+	// WebhookSecret verifies the X-Hub-Signature-256 header of the deliveries.
+	// The deliveries are rejected when it is empty, unless InsecureSkipSignature is set.
+	WebhookSecret []byte
+	// InsecureSkipSignature accepts the deliveries without verifying them when WebhookSecret
+	// is empty, e.g. behind a proxy that verifies them. Anyone who can reach the server can
+	// then trigger the processing of any event.
+	InsecureSkipSignature bool
+	// Authenticate sets the token of the events, e.g. AppTokenSource.Authenticate.
+	Authenticate func(ctx context.Context, event *ActionEvent) error
+	// Metrics are recorded while processing the events and exposed at /metrics when set.
+	Metrics *Metrics
+	// Options configure how the events are processed, e.g. WithTracerProvider to trace them.
+	Options []Option
+}
+
+// Server is an http.Handler that processes the events of the webhooks delivered by GitHub
+// to /webhook and responds with their targets.
+type Server struct {
+	config *ServerConfig
+	mux    *http.ServeMux
+}
+
+// NewServer returns a Server with the given configuration.
+func NewServer(config *ServerConfig) *Server {
+	server := &Server{
+		config: config,
+		mux:    http.NewServeMux(),
+	}
+
+	server.mux.HandleFunc("/webhook", server.handleWebhook)
+
+	if config.Metrics != nil {
+		server.mux.Handle("/metrics", config.Metrics.Handler())
+	}
+
+	return server
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(s.config.WebhookSecret) > 0 || !s.config.InsecureSkipSignature {
+		if err := verifySignature(s.config.WebhookSecret, r.Header.Get("X-Hub-Signature-256"), payload); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	eventName := r.Header.Get("X-GitHub-Event")
+
+	// GitHub sends a 'ping' event when the webhook is created, which has no targets.
+	if eventName == "ping" {
+		writeTargets(w, []*TargetEntity{})
+		return
+	}
+
+	event, err := NewActionEventFromWebhook(eventName, payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if deliveryID := r.Header.Get("X-GitHub-Delivery"); deliveryID != "" {
+		event.DeliveryID = &deliveryID
+	}
+
+	logger := DefaultLogger().WithFields(eventFields(event))
+
+	// The event is authenticated and processed in the trace of the delivery when it has a
+	// traceparent header. The processing is not cancelled when the delivery times out.
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier(r.Header))
+
+	if s.config.Authenticate != nil {
+		if err := s.config.Authenticate(ctx, event); err != nil {
+			logger.Error("failed to authenticate event", Fields{"error": err})
+			http.Error(w, fmt.Sprintf("authenticate: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	opts := append([]Option{WithContext(ctx)}, s.config.Options...)
+	if s.config.Metrics != nil {
+		opts = append([]Option{WithMetrics(s.config.Metrics)}, opts...)
+	}
+
+	targets, err := ProcessEvent(event, opts...)
+	if err != nil {
+		logger.Error("failed to process event", Fields{"error": err})
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeTargets(w, targets)
+}
+
+func writeTargets(w http.ResponseWriter, targets []*TargetEntity) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(targets)
+}
+
+// verifySignature verifies the X-Hub-Signature-256 header of a delivery, which is the HMAC
+// of the payload with the secret of the webhook.
+// For more information, visit: https://docs.github.com/en/developers/webhooks-and-events/webhooks/securing-your-webhooks
+func verifySignature(secret []byte, signature string, payload []byte) error {
+	if len(secret) == 0 {
+		return ErrMissingWebhookSecret
+	}
+
+	digest, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	if !hmac.Equal(digest, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
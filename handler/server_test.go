@@ -0,0 +1,222 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+const issuesWebhookPayload = `{
+	"action": "opened",
+	"repository": {
+		"name": "reviewpad",
+		"full_name": "reviewpad/reviewpad",
+		"owner": {
+			"login": "reviewpad"
+		}
+	},
+	"issue": {
+		"number": 130,
+		"state": "open"
+	}
+}`
+
+func signPayload(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestServer_Webhook(t *testing.T) {
+	authenticate := func(ctx context.Context, event *handler.ActionEvent) error {
+		event.Token = github.String("test-token")
+		return nil
+	}
+
+	tests := map[string]struct {
+		config       *handler.ServerConfig
+		method       string
+		headers      map[string]string
+		payload      string
+		wantStatus   int
+		wantTargets  []*handler.TargetEntity
+		wantResponse string
+	}{
+		"issues": {
+			config:     &handler.ServerConfig{InsecureSkipSignature: true, Authenticate: authenticate},
+			method:     http.MethodPost,
+			headers:    map[string]string{"X-GitHub-Event": "issues"},
+			payload:    issuesWebhookPayload,
+			wantStatus: http.StatusOK,
+			wantTargets: []*handler.TargetEntity{
+				{Kind: handler.Issue, Number: 130, Owner: "reviewpad", Repo: "reviewpad", Reason: "subject of issues event"},
+			},
+		},
+		"signed": {
+			config: &handler.ServerConfig{WebhookSecret: []byte("secret"), Authenticate: authenticate},
+			method: http.MethodPost,
+			headers: map[string]string{
+				"X-GitHub-Event":      "issues",
+				"X-Hub-Signature-256": signPayload("secret", issuesWebhookPayload),
+			},
+			payload:    issuesWebhookPayload,
+			wantStatus: http.StatusOK,
+			wantTargets: []*handler.TargetEntity{
+				{Kind: handler.Issue, Number: 130, Owner: "reviewpad", Repo: "reviewpad", Reason: "subject of issues event"},
+			},
+		},
+		"ping": {
+			config:      &handler.ServerConfig{InsecureSkipSignature: true, Authenticate: authenticate},
+			method:      http.MethodPost,
+			headers:     map[string]string{"X-GitHub-Event": "ping"},
+			payload:     `{"zen": "Keep it logically awesome."}`,
+			wantStatus:  http.StatusOK,
+			wantTargets: []*handler.TargetEntity{},
+		},
+		"invalid_signature": {
+			config: &handler.ServerConfig{WebhookSecret: []byte("secret"), Authenticate: authenticate},
+			method: http.MethodPost,
+			headers: map[string]string{
+				"X-GitHub-Event":      "issues",
+				"X-Hub-Signature-256": signPayload("other-secret", issuesWebhookPayload),
+			},
+			payload:      issuesWebhookPayload,
+			wantStatus:   http.StatusUnauthorized,
+			wantResponse: "invalid webhook signature\n",
+		},
+		"missing_signature": {
+			config:       &handler.ServerConfig{WebhookSecret: []byte("secret"), Authenticate: authenticate},
+			method:       http.MethodPost,
+			headers:      map[string]string{"X-GitHub-Event": "issues"},
+			payload:      issuesWebhookPayload,
+			wantStatus:   http.StatusUnauthorized,
+			wantResponse: "invalid webhook signature\n",
+		},
+		"missing_secret": {
+			config:       &handler.ServerConfig{Authenticate: authenticate},
+			method:       http.MethodPost,
+			headers:      map[string]string{"X-GitHub-Event": "issues"},
+			payload:      issuesWebhookPayload,
+			wantStatus:   http.StatusUnauthorized,
+			wantResponse: "missing webhook secret\n",
+		},
+		"missing_signature_with_insecure_skip_signature": {
+			config:       &handler.ServerConfig{WebhookSecret: []byte("secret"), InsecureSkipSignature: true, Authenticate: authenticate},
+			method:       http.MethodPost,
+			headers:      map[string]string{"X-GitHub-Event": "issues"},
+			payload:      issuesWebhookPayload,
+			wantStatus:   http.StatusUnauthorized,
+			wantResponse: "invalid webhook signature\n",
+		},
+		"missing_event_name": {
+			config:       &handler.ServerConfig{InsecureSkipSignature: true, Authenticate: authenticate},
+			method:       http.MethodPost,
+			payload:      issuesWebhookPayload,
+			wantStatus:   http.StatusBadRequest,
+			wantResponse: "missing event name\n",
+		},
+		"authentication_failure": {
+			config: &handler.ServerConfig{InsecureSkipSignature: true, Authenticate: func(ctx context.Context, event *handler.ActionEvent) error {
+				return errors.New("installation not found")
+			}},
+			method:       http.MethodPost,
+			headers:      map[string]string{"X-GitHub-Event": "issues"},
+			payload:      issuesWebhookPayload,
+			wantStatus:   http.StatusInternalServerError,
+			wantResponse: "authenticate: installation not found\n",
+		},
+		"method_not_allowed": {
+			config:       &handler.ServerConfig{InsecureSkipSignature: true, Authenticate: authenticate},
+			method:       http.MethodGet,
+			wantStatus:   http.StatusMethodNotAllowed,
+			wantResponse: "method not allowed\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "/webhook", strings.NewReader(test.payload))
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.NewServer(test.config).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.wantStatus, rec.Code)
+
+			if test.wantTargets == nil {
+				assert.Equal(t, test.wantResponse, rec.Body.String())
+				return
+			}
+
+			gotTargets := make([]*handler.TargetEntity, 0)
+			if err := json.Unmarshal(rec.Body.Bytes(), &gotTargets); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.wantTargets, gotTargets)
+		})
+	}
+}
+
+func TestServer_Metrics(t *testing.T) {
+	metrics := handler.NewMetrics()
+	server := handler.NewServer(&handler.ServerConfig{
+		InsecureSkipSignature: true,
+		Authenticate: func(ctx context.Context, event *handler.ActionEvent) error {
+			event.Token = github.String("test-token")
+			return nil
+		},
+		Metrics: metrics,
+	})
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client := &http.Client{Transport: &http.Transport{}}
+
+	req, err := http.NewRequest(http.MethodPost, httpServer.URL+"/webhook", bytes.NewBufferString(issuesWebhookPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-GitHub-Event", "issues")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	gotVal := scrapeMetrics(t, server)
+
+	assert.Contains(t, gotVal, `host_event_handler_events_received_total{action="opened",event_name="issues"} 1`)
+	assert.Contains(t, gotVal, `host_event_handler_targets_emitted_total{kind="issue"} 1`)
+}
+
+func TestServer_WithoutMetrics(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	handler.NewServer(&handler.ServerConfig{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
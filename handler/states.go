@@ -0,0 +1,264 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	reviewpad_gh "github.com/reviewpad/reviewpad/v3/codehost/github"
+)
+
+type entityState struct {
+	Closed bool
+	Merged bool
+	Locked bool
+}
+
+type payloadEntity struct {
+	Number      int    `json:"number"`
+	State       string `json:"state"`
+	Locked      bool   `json:"locked"`
+	Merged      bool   `json:"merged"`
+	PullRequest *struct {
+		MergedAt *string `json:"merged_at"`
+	} `json:"pull_request"`
+}
+
+// getPayloadStates returns the state of the pull request, issue or discussion
+// that is the subject of the event, when it is available in the event payload.
+func getPayloadStates(event *ActionEvent) map[string]*entityState {
+	states := make(map[string]*entityState)
+
+	if event.EventPayload == nil {
+		return states
+	}
+
+	payload := struct {
+		Repository struct {
+			Name  string `json:"name"`
+			Owner struct {
+				Login string `json:"login"`
+			} `json:"owner"`
+		} `json:"repository"`
+		PullRequest *payloadEntity `json:"pull_request"`
+		Issue       *payloadEntity `json:"issue"`
+		Discussion  *payloadEntity `json:"discussion"`
+	}{}
+
+	if err := json.Unmarshal(*event.EventPayload, &payload); err != nil {
+		return states
+	}
+
+	entities := map[TargetEntityKind]*payloadEntity{
+		PullRequest: payload.PullRequest,
+		Issue:       payload.Issue,
+		Discussion:  payload.Discussion,
+	}
+
+	for kind, entity := range entities {
+		if entity == nil || entity.State == "" {
+			continue
+		}
+
+		// Comments on pull requests are delivered as 'issue_comment' events, where the
+		// pull request is an issue, which is the kind of the target of the event.
+		key := targetKey(&TargetEntity{
+			Kind:   kind,
+			Number: entity.Number,
+			Owner:  payload.Repository.Owner.Login,
+			Repo:   payload.Repository.Name,
+		})
+
+		states[key] = &entityState{
+			Closed: entity.State == "closed",
+			Merged: entity.Merged || (entity.PullRequest != nil && entity.PullRequest.MergedAt != nil),
+			Locked: entity.Locked || entity.State == "locked",
+		}
+	}
+
+	return states
+}
+
+// setState records the state of a target that was fetched while processing the event,
+// e.g. "open" or "MERGED", so that filterStates does not fetch it again.
+// Empty states, which are missing from the response, are not recorded.
+func (o *options) setState(target *TargetEntity, state string, locked, merged bool) {
+	if state == "" {
+		return
+	}
+
+	merged = merged || strings.EqualFold(state, "merged")
+
+	o.states[targetKey(target)] = &entityState{
+		Closed: merged || strings.EqualFold(state, "closed"),
+		Merged: merged,
+		Locked: locked,
+	}
+}
+
+func (o *options) setPullRequestState(target *TargetEntity, pr *github.PullRequest) {
+	o.setState(target, pr.GetState(), pr.GetLocked(), pr.GetMerged() || pr.MergedAt != nil)
+}
+
+// getOpenStates returns the state of the open issues and pull requests of a repository.
+// Issues and pull requests that are not in the result are closed.
+func getOpenStates(ctx context.Context, ghClient *reviewpad_gh.GithubClient, owner, repo string) (map[int]*entityState, error) {
+	opts := &github.IssueListByRepoOptions{
+		State: "open",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	states := make(map[int]*entityState)
+	for {
+		issues, resp, err := ghClient.ListIssuesByRepo(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range issues {
+			states[*issue.Number] = &entityState{
+				Locked: issue.GetLocked(),
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return states, nil
+}
+
+func getClosedState(ctx context.Context, ghClient *reviewpad_gh.GithubClient, target *TargetEntity) (*entityState, error) {
+	if target.Kind == PullRequest {
+		pr, _, err := ghClient.GetPullRequest(ctx, target.Owner, target.Repo, target.Number)
+		if err != nil {
+			return nil, err
+		}
+
+		return &entityState{
+			Closed: pr.GetState() == "closed",
+			Merged: pr.GetMerged(),
+			Locked: pr.GetLocked(),
+		}, nil
+	}
+
+	issue, _, err := ghClient.GetClientREST().Issues.Get(ctx, target.Owner, target.Repo, target.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entityState{
+		Closed: issue.GetState() == "closed",
+		Locked: issue.GetLocked(),
+	}, nil
+}
+
+// filterState reports why an entity with the given state should be skipped.
+// An empty reason means the entity should be kept.
+func (o *options) filterState(state *entityState) string {
+	if state.Locked && !o.includeLocked {
+		return "locked"
+	}
+
+	if state.Merged && !o.includeMerged {
+		return "merged"
+	}
+
+	if !state.Merged && state.Closed && !o.includeClosed {
+		return "closed"
+	}
+
+	return ""
+}
+
+// filterStates removes the targets that are closed, merged or locked, unless
+// they were explicitly included in the options.
+// The state of the subject of the event is taken from the event payload, and the state
+// of the targets fetched while processing the event is the state they were fetched with.
+// The state of the remaining targets is fetched with a single listing of the open
+// issues and pull requests of each repository.
+// Closed issues and pull requests are only fetched one by one when closed or
+// merged targets are included, to tell them apart.
+func filterStates(event *ActionEvent, targets []*TargetEntity, options *options) ([]*TargetEntity, error) {
+	if options.includeClosed && options.includeMerged && options.includeLocked {
+		return targets, nil
+	}
+
+	ctx, canc := context.WithTimeout(options.ctx, time.Minute*10)
+	defer canc()
+
+	var ghClient *reviewpad_gh.GithubClient
+	if event.Token != nil {
+		ghClient = newGithubClient(ctx, *event.Token, options)
+	}
+
+	states := getPayloadStates(event)
+	openStates := make(map[string]map[int]*entityState)
+
+	filteredTargets := make([]*TargetEntity, 0)
+	for _, target := range targets {
+		state, ok := states[targetKey(target)]
+		if !ok {
+			state, ok = options.states[targetKey(target)]
+		}
+
+		// The state of discussions is only available in the event payload.
+		if !ok && target.Kind == Discussion {
+			state = &entityState{}
+		}
+
+		if state == nil {
+			if ghClient == nil {
+				return nil, fmt.Errorf("get state of %v %v: missing token", target.Kind, target.Number)
+			}
+
+			repoKey := fmt.Sprintf("%v/%v", target.Owner, target.Repo)
+			repoStates, ok := openStates[repoKey]
+			if !ok {
+				var err error
+				repoStates, err = getOpenStates(ctx, ghClient, target.Owner, target.Repo)
+				if err != nil {
+					return nil, fmt.Errorf("get open issues and pull requests: %w", err)
+				}
+				openStates[repoKey] = repoStates
+			}
+
+			state = repoStates[target.Number]
+		}
+
+		if state == nil {
+			if !options.includeClosed && !options.includeMerged {
+				options.logger.Info("skipping target", targetFields(target), Fields{"reason": "closed"})
+				options.drop(target, "closed")
+				continue
+			}
+
+			var err error
+			state, err = getClosedState(ctx, ghClient, target)
+			if err != nil {
+				return nil, fmt.Errorf("get state of %v %v: %w", target.Kind, target.Number, err)
+			}
+		}
+
+		if reason := options.filterState(state); reason != "" {
+			options.logger.Info("skipping target", targetFields(target), Fields{"reason": reason})
+			options.drop(target, reason)
+			continue
+		}
+
+		filteredTargets = append(filteredTargets, target)
+	}
+
+	return filteredTargets, nil
+}
@@ -0,0 +1,106 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the instrumentation library of the spans of the handler.
+const tracerName = "github.com/reviewpad/host-event-handler/handler"
+
+// startSpan starts a span as a child of the span in the context of the options.
+// The returned options carry the new span, so that the spans started with them,
+// including the spans of the requests to the GitHub API, are its children.
+func (o *options) startSpan(name string, attrs ...attribute.KeyValue) (*options, trace.Span) {
+	ctx, span := o.tracer.Start(o.ctx, name, trace.WithAttributes(attrs...))
+
+	child := *o
+	child.ctx = ctx
+
+	return &child, span
+}
+
+// traceStep runs a step of the processing of an event, e.g. "processStatusEvent", in a span
+// that records the number of targets it returned or its error.
+func traceStep(name string, opts *options, step func(opts *options) ([]*TargetEntity, error)) ([]*TargetEntity, error) {
+	opts, span := opts.startSpan(name)
+	defer span.End()
+
+	targets, err := step(opts)
+	recordResult(span, targets, err)
+
+	return targets, err
+}
+
+// recordResult records the number of targets or the error in the span.
+func recordResult(span trace.Span, targets []*TargetEntity, err error) {
+	if err != nil {
+		recordError(span, err)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("targets.count", len(targets)))
+}
+
+// recordError records the error in the span and marks it as failed.
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// eventAttributes are the attributes that identify the event in the spans.
+func eventAttributes(event *ActionEvent) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("event.name", *event.EventName),
+		attribute.String("event.action", getEventAction(event)),
+	}
+
+	if event.DeliveryID != nil {
+		attrs = append(attrs, attribute.String("github.delivery_id", *event.DeliveryID))
+	}
+
+	if event.Repository != nil {
+		attrs = append(attrs, attribute.String("github.repository", *event.Repository))
+	}
+
+	return attrs
+}
+
+// tracingTransport is an http.RoundTripper that records a span for each request to the GitHub API.
+// The span covers the retries of the request and the time spent waiting between them.
+type tracingTransport struct {
+	base   http.RoundTripper
+	tracer trace.Tracer
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), "GitHub "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(req.Method),
+			semconv.HTTPURLKey.String(req.URL.String()),
+		),
+	)
+	defer span.End()
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+
+	return resp, nil
+}
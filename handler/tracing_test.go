@@ -0,0 +1,161 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+// spansByName returns the ended spans by name, with the spans of the requests named after their path.
+func spansByName(exporter *tracetest.InMemoryExporter) map[string]tracetest.SpanStub {
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		name := span.Name
+		for _, attr := range span.Attributes {
+			if attr.Key == "http.url" {
+				if requestURL, err := url.Parse(attr.Value.AsString()); err == nil {
+					name = fmt.Sprintf("%v %v", span.Name, requestURL.Path)
+				}
+			}
+		}
+		spans[name] = span
+	}
+	return spans
+}
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestProcessEvent_WithTracerProvider(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/pulls",
+		httpmock.NewStringResponder(200, `[{"number": 6, "head": {"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g"}, "base": {"repo": {"name": "reviewpad", "owner": {"login": "reviewpad"}}}}]`),
+	)
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/issues",
+		httpmock.NewStringResponder(200, `[{"number": 6, "pull_request": {}}]`),
+	)
+
+	provider, exporter := newTracerProvider()
+
+	parentCtx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	targets, err := handler.ProcessEvent(
+		statusEvent("4bf24cc72f3a62423927a0ac8d70febad7c78e0g"),
+		handler.WithTracerProvider(provider),
+		handler.WithContext(parentCtx),
+	)
+	parent.End()
+
+	assert.Nil(t, err)
+	assert.Len(t, targets, 1)
+
+	spans := spansByName(exporter)
+
+	wantParents := map[string]string{
+		"ProcessEvent":       "parent",
+		"parseEventPayload":  "ProcessEvent",
+		"processStatusEvent": "ProcessEvent",
+		"GitHub GET /repos/reviewpad/reviewpad/pulls": "processStatusEvent",
+		"filterStates": "ProcessEvent",
+		"GitHub GET /repos/reviewpad/reviewpad/issues": "filterStates",
+	}
+	for name, parentName := range wantParents {
+		span, ok := spans[name]
+		if !assert.True(t, ok, "missing span %v", name) {
+			continue
+		}
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID(), name)
+		assert.Equal(t, spans[parentName].SpanContext.SpanID(), span.Parent.SpanID(), name)
+	}
+
+	assert.Equal(t, "status", spanAttribute(spans["ProcessEvent"], "event.name").AsString())
+	assert.Equal(t, int64(1), spanAttribute(spans["ProcessEvent"], "targets.count").AsInt64())
+	assert.Equal(t, int64(1), spanAttribute(spans["processStatusEvent"], "targets.count").AsInt64())
+
+	requestSpan := spans["GitHub GET /repos/reviewpad/reviewpad/pulls"]
+	assert.Equal(t, trace.SpanKindClient, requestSpan.SpanKind)
+	assert.Equal(t, int64(200), spanAttribute(requestSpan, "http.status_code").AsInt64())
+}
+
+func TestProcessEvent_WithTracerProvider_Failure(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/reviewpad/reviewpad/pulls",
+		httpmock.NewStringResponder(404, `{"message": "Not Found"}`),
+	)
+
+	provider, exporter := newTracerProvider()
+
+	event := statusEvent("4bf24cc72f3a62423927a0ac8d70febad7c78e0g")
+	_, err := handler.ProcessEvent(event, handler.WithTracerProvider(provider))
+
+	assert.NotNil(t, err)
+
+	spans := spansByName(exporter)
+
+	assert.Equal(t, codes.Error, spans["ProcessEvent"].Status.Code)
+	assert.Equal(t, codes.Error, spans["processStatusEvent"].Status.Code)
+	assert.Equal(t, codes.Error, spans["GitHub GET /repos/reviewpad/reviewpad/pulls"].Status.Code)
+	assert.Equal(t, codes.Unset, spans["parseEventPayload"].Status.Code)
+}
+
+func TestServer_Traceparent(t *testing.T) {
+	provider, exporter := newTracerProvider()
+
+	server := handler.NewServer(&handler.ServerConfig{
+		InsecureSkipSignature: true,
+		Authenticate: func(ctx context.Context, event *handler.ActionEvent) error {
+			event.Token = github.String("test-token")
+			return nil
+		},
+		Options: []handler.Option{handler.WithTracerProvider(provider)},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(issuesWebhookPayload))
+	req.Header.Set("X-GitHub-Event", "issues")
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+
+	server.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	span := spansByName(exporter)["ProcessEvent"]
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.True(t, span.Parent.IsRemote())
+	assert.Equal(t, "issues", spanAttribute(span, "event.name").AsString())
+	assert.Equal(t, "opened", spanAttribute(span, "event.action").AsString())
+}
@@ -4,7 +4,16 @@
 
 package handler
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
 
 // ActionEvent contains information about the workflow run and the event that triggered the run.
 // For more information, visit: https://docs.github.com/en/actions/learn-github-actions/contexts#github-context
@@ -41,4 +50,310 @@ type ActionEvent struct {
 	Token            *string          `json:"token,omitempty"`
 	Workflow         *string          `json:"workflow,omitempty"`
 	Workspace        *string          `json:"workspace,omitempty"`
+
+	// DeliveryID is the unique ID of the webhook delivery of the event, i.e. the X-GitHub-Delivery header.
+	// It is not part of the github context.
+	DeliveryID *string `json:"-"`
+
+	// Extra holds the fields of the github context that are not known to the handler,
+	// so that they are kept when the event is encoded again.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// actionEvent has the fields of ActionEvent without its custom JSON encoding.
+type actionEvent ActionEvent
+
+// actionEventFields are the JSON names of the fields of ActionEvent.
+var actionEventFields = jsonFieldNames(reflect.TypeOf(ActionEvent{}))
+
+// actionEventScalarFields are the fields of the github context that are
+// decoded as strings but may be serialized as numbers or booleans.
+var actionEventScalarFields = []string{"run_id", "run_number", "run_attempt", "retention_days"}
+
+// UnmarshalJSON decodes the github context, accepting the different forms that some
+// of its fields take depending on how the workflow serializes the context:
+// run_id, run_number, run_attempt and retention_days may be strings or numbers,
+// env may be the path of the env file or an object with the environment variables,
+// and ref_protected may be a boolean or a string.
+// Unknown fields are kept in Extra.
+func (e *ActionEvent) UnmarshalJSON(data []byte) error {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	for _, name := range actionEventScalarFields {
+		if raw, ok := fields[name]; ok {
+			value, err := decodeScalar(raw)
+			if err != nil {
+				return fmt.Errorf("decode %v: %w", name, err)
+			}
+			fields[name] = value
+		}
+	}
+
+	if raw, ok := fields["env"]; ok {
+		value, err := decodeEnv(raw)
+		if err != nil {
+			return fmt.Errorf("decode env: %w", err)
+		}
+		fields["env"] = value
+	}
+
+	if raw, ok := fields["ref_protected"]; ok {
+		value, err := decodeBool(raw)
+		if err != nil {
+			return fmt.Errorf("decode ref_protected: %w", err)
+		}
+		fields["ref_protected"] = value
+	}
+
+	var extra map[string]json.RawMessage
+	for name, raw := range fields {
+		if _, ok := actionEventFields[name]; ok {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[name] = raw
+		delete(fields, name)
+	}
+
+	normalized, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	event := actionEvent{}
+	if err := json.Unmarshal(normalized, &event); err != nil {
+		return err
+	}
+
+	// The event payload is kept as it was received, since encoding the fields
+	// again compacts it.
+	if event.EventPayload != nil {
+		payload := fields["event"]
+		event.EventPayload = &payload
+	}
+
+	*e = ActionEvent(event)
+	e.Extra = extra
+
+	return nil
+}
+
+// MarshalJSON encodes the github context, including the unknown fields kept in Extra.
+func (e *ActionEvent) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal((*actionEvent)(e))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(e.Extra) == 0 {
+		return data, nil
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for name, raw := range e.Extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = raw
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// GetRunID returns the unique number of the workflow run.
+func (e *ActionEvent) GetRunID() (int64, error) {
+	return parseInt("run_id", e.RunID)
+}
+
+// GetRunNumber returns the number of the run of the workflow.
+func (e *ActionEvent) GetRunNumber() (int64, error) {
+	return parseInt("run_number", e.RunNumber)
+}
+
+// GetRunAttempt returns the number of the attempt of the workflow run.
+func (e *ActionEvent) GetRunAttempt() (int64, error) {
+	return parseInt("run_attempt", e.RunAttempt)
+}
+
+// GetEnv returns the environment variables when the env field of the github context
+// is an object, e.g. when the env context is merged into the event.
+// When the env field is the path of the env file, there are no variables to return.
+func (e *ActionEvent) GetEnv() (map[string]string, error) {
+	if e.Env == nil {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(*e.Env), "{") {
+		return nil, fmt.Errorf("env is the path %v, not an object", *e.Env)
+	}
+
+	env := make(map[string]string)
+	if err := json.Unmarshal([]byte(*e.Env), &env); err != nil {
+		return nil, fmt.Errorf("decode env: %w", err)
+	}
+
+	return env, nil
+}
+
+func parseInt(name string, value *string) (int64, error) {
+	if value == nil {
+		return 0, fmt.Errorf("missing %v", name)
+	}
+
+	n, err := strconv.ParseInt(*value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %v: %w", name, err)
+	}
+
+	return n, nil
+}
+
+// decodeScalar converts a JSON number or boolean into a JSON string.
+func decodeScalar(raw json.RawMessage) (json.RawMessage, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case nil, string:
+		return raw, nil
+	case json.Number:
+		return json.Marshal(v.String())
+	case bool:
+		return json.Marshal(strconv.FormatBool(v))
+	default:
+		return nil, fmt.Errorf("unexpected value %s", raw)
+	}
+}
+
+// decodeEnv converts an object with the environment variables into a JSON string.
+func decodeEnv(raw json.RawMessage) (json.RawMessage, error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+
+	switch value.(type) {
+	case nil, string:
+		return raw, nil
+	case map[string]interface{}:
+		var env bytes.Buffer
+		if err := json.Compact(&env, raw); err != nil {
+			return nil, err
+		}
+		return json.Marshal(env.String())
+	default:
+		return nil, fmt.Errorf("unexpected value %s", raw)
+	}
+}
+
+// decodeBool converts a JSON string with a boolean into a JSON boolean.
+func decodeBool(raw json.RawMessage) (json.RawMessage, error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case nil, bool:
+		return raw, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(b)
+	default:
+		return nil, fmt.Errorf("unexpected value %s", raw)
+	}
+}
+
+func jsonFieldNames(t reflect.Type) map[string]struct{} {
+	names := make(map[string]struct{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names[name] = struct{}{}
+		}
+	}
+	return names
+}
+
+// DiscussionCommentEvent is triggered when a comment on a discussion is created, edited or deleted.
+// The go-github library does not support this webhook event yet, so it is decoded by the handler.
+// For more information, visit: https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#discussion_comment
+type DiscussionCommentEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	Comment      *DiscussionComment   `json:"comment,omitempty"`
+	Discussion   *github.Discussion   `json:"discussion,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Org          *github.Organization `json:"organization,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// DiscussionComment represents a comment on a discussion in a DiscussionCommentEvent.
+type DiscussionComment struct {
+	ID                *int64            `json:"id,omitempty"`
+	NodeID            *string           `json:"node_id,omitempty"`
+	HTMLURL           *string           `json:"html_url,omitempty"`
+	ParentID          *int64            `json:"parent_id,omitempty"`
+	Body              *string           `json:"body,omitempty"`
+	User              *github.User      `json:"user,omitempty"`
+	AuthorAssociation *string           `json:"author_association,omitempty"`
+	CreatedAt         *github.Timestamp `json:"created_at,omitempty"`
+	UpdatedAt         *github.Timestamp `json:"updated_at,omitempty"`
+}
+
+// ProjectsV2ItemEvent is triggered when an item in a project (v2) is created, edited, deleted,
+// archived, restored, converted or reordered.
+// The go-github library does not support this webhook event yet, so it is decoded by the handler.
+// For more information, visit: https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#projects_v2_item
+type ProjectsV2ItemEvent struct {
+	Action         *string              `json:"action,omitempty"`
+	ProjectsV2Item *ProjectsV2Item      `json:"projects_v2_item,omitempty"`
+	Changes        *json.RawMessage     `json:"changes,omitempty"`
+	Org            *github.Organization `json:"organization,omitempty"`
+	Sender         *github.User         `json:"sender,omitempty"`
+	Installation   *github.Installation `json:"installation,omitempty"`
+}
+
+// ProjectsV2Item represents an item of a project (v2) in a ProjectsV2ItemEvent.
+type ProjectsV2Item struct {
+	ID            *int64            `json:"id,omitempty"`
+	NodeID        *string           `json:"node_id,omitempty"`
+	ProjectNodeID *string           `json:"project_node_id,omitempty"`
+	ContentNodeID *string           `json:"content_node_id,omitempty"`
+	ContentType   *string           `json:"content_type,omitempty"`
+	Creator       *github.User      `json:"creator,omitempty"`
+	CreatedAt     *github.Timestamp `json:"created_at,omitempty"`
+	UpdatedAt     *github.Timestamp `json:"updated_at,omitempty"`
+	ArchivedAt    *github.Timestamp `json:"archived_at,omitempty"`
+}
+
+// GetContentNodeID returns the ContentNodeID field if it's non-nil, zero value otherwise.
+func (p *ProjectsV2Item) GetContentNodeID() string {
+	if p == nil || p.ContentNodeID == nil {
+		return ""
+	}
+	return *p.ContentNodeID
+}
+
+// GetContentType returns the ContentType field if it's non-nil, zero value otherwise.
+func (p *ProjectsV2Item) GetContentType() string {
+	if p == nil || p.ContentType == nil {
+		return ""
+	}
+	return *p.ContentType
 }
@@ -0,0 +1,239 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+// toJSON(github) in a workflow step, with the token masked by GitHub Actions.
+var githubContextDump = `{
+	"token": "***",
+	"job": "reviewpad",
+	"ref": "refs/pull/130/merge",
+	"sha": "4bf24cc72f3a62423927a0ac8d70febad7c78e0g",
+	"repository": "reviewpad/reviewpad",
+	"repository_owner": "reviewpad",
+	"repository_owner_id": "78365138",
+	"repositoryUrl": "git://github.com/reviewpad/reviewpad.git",
+	"run_id": "2885318453",
+	"run_number": "1012",
+	"retention_days": "90",
+	"run_attempt": "1",
+	"artifact_cache_size_limit": "10",
+	"actor": "john",
+	"triggering_actor": "john",
+	"workflow": "Reviewpad",
+	"head_ref": "feat/tolerant-decoding",
+	"base_ref": "main",
+	"event_name": "pull_request",
+	"event": {"action": "opened", "number": 130},
+	"server_url": "https://github.com",
+	"api_url": "https://api.github.com",
+	"graphql_url": "https://api.github.com/graphql",
+	"ref_name": "130/merge",
+	"ref_protected": false,
+	"ref_type": "branch",
+	"secret_source": "Actions",
+	"workspace": "/home/runner/work/reviewpad/reviewpad",
+	"action": "__run",
+	"event_path": "/home/runner/work/_temp/_github_workflow/event.json",
+	"action_repository": "",
+	"action_ref": "",
+	"path": "/home/runner/work/_temp/_runner_file_commands/add_path_1",
+	"env": "/home/runner/work/_temp/_runner_file_commands/set_env_1"
+}`
+
+// The github context re-serialized by a script, with the run fields as numbers
+// and the env context merged into it.
+var githubContextScriptDump = `{
+	"token": "***",
+	"repository": "reviewpad/reviewpad",
+	"run_id": 2885318453,
+	"run_number": 1012,
+	"run_attempt": 2,
+	"retention_days": 90,
+	"event_name": "schedule",
+	"event": {"schedule": "0 0 * * *"},
+	"ref_protected": "true",
+	"env": {"GO_VERSION": "1.18", "CI": "true"}
+}`
+
+func TestParseEvent_GithubContext(t *testing.T) {
+	payload := json.RawMessage(`{"action": "opened", "number": 130}`)
+
+	gotEvent, err := handler.ParseEvent(githubContextDump)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &handler.ActionEvent{
+		ActionName:       github.String("__run"),
+		ActionRef:        github.String(""),
+		ActionRepository: github.String(""),
+		Actor:            github.String("john"),
+		ApiUrl:           github.String("https://api.github.com"),
+		BaseRef:          github.String("main"),
+		HeadRef:          github.String("feat/tolerant-decoding"),
+		Env:              github.String("/home/runner/work/_temp/_runner_file_commands/set_env_1"),
+		EventPayload:     &payload,
+		EventName:        github.String("pull_request"),
+		EventPath:        github.String("/home/runner/work/_temp/_github_workflow/event.json"),
+		QraphqlUrl:       github.String("https://api.github.com/graphql"),
+		JobID:            github.String("reviewpad"),
+		Ref:              github.String("refs/pull/130/merge"),
+		RefName:          github.String("130/merge"),
+		RefProtected:     github.Bool(false),
+		RefType:          github.String("branch"),
+		Path:             github.String("/home/runner/work/_temp/_runner_file_commands/add_path_1"),
+		Repository:       github.String("reviewpad/reviewpad"),
+		RepositoryOwner:  github.String("reviewpad"),
+		RepositoryUrl:    github.String("git://github.com/reviewpad/reviewpad.git"),
+		RetentionDays:    github.String("90"),
+		RunID:            github.String("2885318453"),
+		RunNumber:        github.String("1012"),
+		RunAttempt:       github.String("1"),
+		ServerUrl:        github.String("https://github.com"),
+		SHA:              github.String("4bf24cc72f3a62423927a0ac8d70febad7c78e0g"),
+		Token:            github.String("***"),
+		Workflow:         github.String("Reviewpad"),
+		Workspace:        github.String("/home/runner/work/reviewpad/reviewpad"),
+		Extra: map[string]json.RawMessage{
+			"repository_owner_id":       json.RawMessage(`"78365138"`),
+			"artifact_cache_size_limit": json.RawMessage(`"10"`),
+			"triggering_actor":          json.RawMessage(`"john"`),
+			"secret_source":             json.RawMessage(`"Actions"`),
+		},
+	}, gotEvent)
+}
+
+func TestParseEvent_GithubContextScript(t *testing.T) {
+	payload := json.RawMessage(`{"schedule": "0 0 * * *"}`)
+
+	gotEvent, err := handler.ParseEvent(githubContextScriptDump)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &handler.ActionEvent{
+		Env:           github.String(`{"GO_VERSION":"1.18","CI":"true"}`),
+		EventPayload:  &payload,
+		EventName:     github.String("schedule"),
+		RefProtected:  github.Bool(true),
+		Repository:    github.String("reviewpad/reviewpad"),
+		RetentionDays: github.String("90"),
+		RunID:         github.String("2885318453"),
+		RunNumber:     github.String("1012"),
+		RunAttempt:    github.String("2"),
+		Token:         github.String("***"),
+	}, gotEvent)
+}
+
+func TestParseEvent_GithubContext_Failure(t *testing.T) {
+	tests := map[string]struct {
+		rawEvent string
+	}{
+		"run_id_object": {
+			rawEvent: `{"run_id": {"id": 1}}`,
+		},
+		"env_number": {
+			rawEvent: `{"env": 1}`,
+		},
+		"ref_protected_not_bool": {
+			rawEvent: `{"ref_protected": "maybe"}`,
+		},
+		"event_name_number": {
+			rawEvent: `{"event_name": 1}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotEvent, err := handler.ParseEvent(test.rawEvent)
+
+			assert.NotNil(t, err)
+			assert.Nil(t, gotEvent)
+		})
+	}
+}
+
+func TestActionEvent_MarshalJSON(t *testing.T) {
+	event, err := handler.ParseEvent(`{"event_name": "push", "run_id": 42, "triggering_actor": "john"}`)
+	assert.Nil(t, err)
+
+	gotVal, err := json.Marshal(event)
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"event_name": "push", "run_id": "42", "triggering_actor": "john"}`, string(gotVal))
+}
+
+func TestActionEvent_Accessors(t *testing.T) {
+	tests := map[string]struct {
+		rawEvent       string
+		wantRunID      int64
+		wantRunNumber  int64
+		wantRunAttempt int64
+		wantEnv        map[string]string
+	}{
+		"github_context": {
+			rawEvent:       githubContextDump,
+			wantRunID:      2885318453,
+			wantRunNumber:  1012,
+			wantRunAttempt: 1,
+		},
+		"github_context_script": {
+			rawEvent:       githubContextScriptDump,
+			wantRunID:      2885318453,
+			wantRunNumber:  1012,
+			wantRunAttempt: 2,
+			wantEnv: map[string]string{
+				"GO_VERSION": "1.18",
+				"CI":         "true",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			event, err := handler.ParseEvent(test.rawEvent)
+			assert.Nil(t, err)
+
+			runID, err := event.GetRunID()
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantRunID, runID)
+
+			runNumber, err := event.GetRunNumber()
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantRunNumber, runNumber)
+
+			runAttempt, err := event.GetRunAttempt()
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantRunAttempt, runAttempt)
+
+			if test.wantEnv != nil {
+				env, err := event.GetEnv()
+				assert.Nil(t, err)
+				assert.Equal(t, test.wantEnv, env)
+			}
+		})
+	}
+}
+
+func TestActionEvent_Accessors_Failure(t *testing.T) {
+	event := &handler.ActionEvent{
+		Env:       github.String("/home/runner/work/_temp/_runner_file_commands/set_env_1"),
+		RunNumber: github.String("first"),
+	}
+
+	_, err := event.GetRunID()
+	assert.NotNil(t, err)
+
+	_, err = event.GetRunNumber()
+	assert.NotNil(t, err)
+
+	_, err = event.GetEnv()
+	assert.NotNil(t, err)
+}
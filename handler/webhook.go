@@ -0,0 +1,46 @@
+// Copyright (C) 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// NewActionEventFromWebhook assembles an ActionEvent from the body of a webhook delivery,
+// e.g. copied from the "Recent Deliveries" of a repository or GitHub App, and its event
+// name, i.e. the X-GitHub-Event header.
+// The repository of the event is taken from the payload.
+func NewActionEventFromWebhook(eventName string, payload []byte) (*ActionEvent, error) {
+	if eventName == "" {
+		return nil, fmt.Errorf("missing event name")
+	}
+
+	webhook := struct {
+		Repository *struct {
+			FullName string `json:"full_name"`
+			Owner    struct {
+				Login string `json:"login"`
+			} `json:"owner"`
+		} `json:"repository"`
+	}{}
+
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return nil, fmt.Errorf("parse webhook payload: %w", err)
+	}
+
+	rawPayload := json.RawMessage(payload)
+	event := &ActionEvent{
+		EventName:    &eventName,
+		EventPayload: &rawPayload,
+	}
+
+	if webhook.Repository != nil {
+		event.Repository = &webhook.Repository.FullName
+		event.RepositoryOwner = &webhook.Repository.Owner.Login
+	}
+
+	return event, nil
+}
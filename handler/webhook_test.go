@@ -0,0 +1,96 @@
+// Copyright 2022 Explore.dev Unipessoal Lda. All Rights Reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/reviewpad/host-event-handler/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewActionEventFromWebhook(t *testing.T) {
+	tests := map[string]struct {
+		eventName string
+		payload   string
+		wantVal   *handler.ActionEvent
+	}{
+		"pull_request": {
+			eventName: "pull_request",
+			payload:   `{"action": "opened", "number": 130, "repository": {"full_name": "reviewpad/reviewpad", "owner": {"login": "reviewpad"}}}`,
+			wantVal: &handler.ActionEvent{
+				EventName:       github.String("pull_request"),
+				EventPayload:    buildPayload([]byte(`{"action": "opened", "number": 130, "repository": {"full_name": "reviewpad/reviewpad", "owner": {"login": "reviewpad"}}}`)),
+				Repository:      github.String("reviewpad/reviewpad"),
+				RepositoryOwner: github.String("reviewpad"),
+			},
+		},
+		"without_repository": {
+			eventName: "installation",
+			payload:   `{"action": "created"}`,
+			wantVal: &handler.ActionEvent{
+				EventName:    github.String("installation"),
+				EventPayload: buildPayload([]byte(`{"action": "created"}`)),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.NewActionEventFromWebhook(test.eventName, []byte(test.payload))
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantVal, gotVal)
+		})
+	}
+}
+
+func TestNewActionEventFromWebhook_Failure(t *testing.T) {
+	tests := map[string]struct {
+		eventName string
+		payload   string
+	}{
+		"missing_event_name": {
+			payload: `{"action": "opened"}`,
+		},
+		"invalid_payload": {
+			eventName: "pull_request",
+			payload:   `{,}`,
+		},
+		"not_an_object": {
+			eventName: "pull_request",
+			payload:   `[]`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotVal, err := handler.NewActionEventFromWebhook(test.eventName, []byte(test.payload))
+
+			assert.NotNil(t, err)
+			assert.Nil(t, gotVal)
+		})
+	}
+}
+
+func TestNewActionEventFromWebhook_ProcessEvent(t *testing.T) {
+	event, err := handler.NewActionEventFromWebhook("pull_request", []byte(`{"action": "opened", "number": 130, "pull_request": {"number": 130, "state": "open"}, "repository": {"name": "reviewpad", "full_name": "reviewpad/reviewpad", "owner": {"login": "reviewpad"}}}`))
+	assert.Nil(t, err)
+
+	event.Token = github.String("test-token")
+	gotVal, err := handler.ProcessEvent(event)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*handler.TargetEntity{
+		{
+			Kind:   handler.PullRequest,
+			Number: 130,
+			Owner:  "reviewpad",
+			Repo:   "reviewpad",
+			Reason: "subject of pull_request event",
+		},
+	}, gotVal)
+}
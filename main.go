@@ -5,28 +5,86 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/reviewpad/host-event-handler/handler"
 )
 
-func getEnvVariable(name string) string {
-	value, ok := os.LookupEnv(name)
-	if !ok {
-		log.Fatal(fmt.Errorf("missing %s env variable", name))
+func getEvent() (*handler.ActionEvent, error) {
+	// The whole github context can be provided as the "event" input,
+	// otherwise the event is assembled from the default environment variables.
+	rawEvent, ok := os.LookupEnv("INPUT_EVENT")
+	if !ok || rawEvent == "" {
+		return handler.NewActionEventFromEnv(os.LookupEnv)
 	}
-	return value
+
+	return handler.ParseEvent(rawEvent)
+}
+
+// getAppConfig returns the GitHub App to authenticate as, when the "app_id" and
+// "app_private_key" inputs are provided.
+func getAppConfig() (*handler.AppConfig, error) {
+	appID := os.Getenv("INPUT_APP_ID")
+	if appID == "" {
+		return nil, nil
+	}
+
+	config := &handler.AppConfig{
+		PrivateKey: []byte(os.Getenv("INPUT_APP_PRIVATE_KEY")),
+	}
+
+	var err error
+	config.AppID, err = strconv.ParseInt(appID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if installationID := os.Getenv("INPUT_APP_INSTALLATION_ID"); installationID != "" {
+		config.InstallationID, err = strconv.ParseInt(installationID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
 }
 
 func main() {
-	rawEvent := getEnvVariable("INPUT_EVENT")
+	event, err := getEvent()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	event, err := handler.ParseEvent(rawEvent)
+	appConfig, err := getAppConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	handler.ProcessEvent(event)
+	opts, err := handler.NewOptionsFromEnv(os.LookupEnv)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if appConfig != nil {
+		source, err := handler.NewAppTokenSource(appConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := source.Authenticate(context.Background(), event); err != nil {
+			log.Fatal(err)
+		}
+
+		// The events triggered by the changes made as the GitHub App are skipped.
+		login, err := source.Login(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, handler.WithBotLogins(login))
+	}
+
+	handler.ProcessEvent(event, opts...)
 }